@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS tg_max_3_targets ON targets;
+DROP FUNCTION IF EXISTS ensure_max_3_targets();
+DROP INDEX IF EXISTS ux_active_mission_per_cat;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS ux_targets_mission_name;
@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS mission_transitions;
+
+
+ALTER TABLE missions ADD COLUMN completed BOOLEAN NOT NULL DEFAULT false;
+-- aborted and failed missions are finished too; keep them from blocking their cat
+UPDATE missions SET completed = state IN ('completed', 'aborted', 'failed');
+
+
+DROP INDEX IF EXISTS ux_active_mission_per_cat;
+CREATE UNIQUE INDEX ux_active_mission_per_cat
+ON missions(assigned_cat_id)
+WHERE assigned_cat_id IS NOT NULL AND completed = false;
+
+
+ALTER TABLE missions DROP COLUMN state, DROP COLUMN state_changed_at;
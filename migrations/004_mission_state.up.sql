@@ -0,0 +1,36 @@
+-- Replace missions.completed with an explicit lifecycle state
+ALTER TABLE missions
+ADD COLUMN state TEXT NOT NULL DEFAULT 'draft'
+CHECK (state IN ('draft', 'assigned', 'active', 'completed', 'aborted', 'failed')),
+ADD COLUMN state_changed_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+
+-- Open missions with a cat were being worked on, so they become active
+UPDATE missions SET
+state = CASE
+WHEN completed THEN 'completed'
+WHEN assigned_cat_id IS NOT NULL THEN 'active'
+ELSE 'draft'
+END,
+state_changed_at = updated_at;
+
+
+-- A cat may hold at most one assigned or active mission
+DROP INDEX IF EXISTS ux_active_mission_per_cat;
+CREATE UNIQUE INDEX ux_active_mission_per_cat
+ON missions(assigned_cat_id)
+WHERE assigned_cat_id IS NOT NULL AND state IN ('assigned', 'active');
+
+
+ALTER TABLE missions DROP COLUMN completed;
+
+
+CREATE TABLE mission_transitions (
+id BIGSERIAL PRIMARY KEY,
+mission_id BIGINT NOT NULL REFERENCES missions(id) ON DELETE CASCADE,
+from_state TEXT NOT NULL,
+to_state TEXT NOT NULL,
+reason TEXT NOT NULL DEFAULT '',
+created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX ix_mission_transitions_mission ON mission_transitions(mission_id, id);
@@ -0,0 +1,4 @@
+ALTER TABLE missions
+DROP CONSTRAINT IF EXISTS chk_missions_forced_reason,
+DROP COLUMN IF EXISTS completion_reason,
+DROP COLUMN IF EXISTS completion_forced;
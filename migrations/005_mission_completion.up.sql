@@ -0,0 +1,5 @@
+-- Completions that skipped open targets are flagged and must say why
+ALTER TABLE missions
+ADD COLUMN completion_forced BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN completion_reason TEXT NOT NULL DEFAULT '',
+ADD CONSTRAINT chk_missions_forced_reason CHECK (NOT completion_forced OR completion_reason <> '');
@@ -0,0 +1,20 @@
+-- Cat hand-overs; cat IDs are kept without a foreign key so history
+-- survives deleting the cat
+CREATE TABLE mission_assignments (
+id BIGSERIAL PRIMARY KEY,
+mission_id BIGINT NOT NULL REFERENCES missions(id) ON DELETE CASCADE,
+from_cat_id BIGINT,
+to_cat_id BIGINT,
+actor TEXT NOT NULL DEFAULT '',
+reason TEXT NOT NULL DEFAULT '',
+created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+CHECK (from_cat_id IS NOT NULL OR to_cat_id IS NOT NULL)
+);
+CREATE INDEX ix_mission_assignments_mission ON mission_assignments(mission_id, id);
+
+
+-- Missions assigned before this table existed get a synthetic first entry
+INSERT INTO mission_assignments (mission_id, to_cat_id, reason, created_at)
+SELECT id, assigned_cat_id, 'backfilled', created_at
+FROM missions
+WHERE assigned_cat_id IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Static API keys; only a SHA-256 hash of the secret is stored. The
+-- prefix is the public part of the key, used to find the row.
+CREATE TABLE api_keys (
+id BIGSERIAL PRIMARY KEY,
+name TEXT NOT NULL,
+prefix TEXT NOT NULL UNIQUE,
+hash TEXT NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+last_used_at TIMESTAMPTZ,
+revoked_at TIMESTAMPTZ
+);
@@ -0,0 +1,5 @@
+ALTER TABLE api_keys
+DROP CONSTRAINT IF EXISTS chk_api_keys_cat,
+DROP CONSTRAINT IF EXISTS chk_api_keys_role,
+DROP COLUMN IF EXISTS cat_id,
+DROP COLUMN IF EXISTS role;
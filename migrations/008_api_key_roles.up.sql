@@ -0,0 +1,10 @@
+-- Every key carries one role; keys of the cat role act for one cat.
+-- Keys created before roles existed keep managing missions as handlers.
+ALTER TABLE api_keys
+ADD COLUMN role TEXT NOT NULL DEFAULT 'handler',
+ADD COLUMN cat_id BIGINT NULL REFERENCES cats(id) ON DELETE CASCADE,
+ADD CONSTRAINT chk_api_keys_role CHECK (role IN ('handler', 'cat', 'finance')),
+ADD CONSTRAINT chk_api_keys_cat CHECK ((role = 'cat') = (cat_id IS NOT NULL));
+
+
+ALTER TABLE api_keys ALTER COLUMN role DROP DEFAULT;
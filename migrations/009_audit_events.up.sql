@@ -0,0 +1,16 @@
+-- Who changed what: one row per mutation, written in the same transaction.
+-- Entity IDs have no foreign key so the trail survives deletions.
+CREATE TABLE audit_events (
+id BIGSERIAL PRIMARY KEY,
+actor TEXT NOT NULL DEFAULT '',
+action TEXT NOT NULL,
+entity_type TEXT NOT NULL,
+entity_id BIGINT NOT NULL,
+changes JSONB NOT NULL DEFAULT '{}',
+request_id TEXT NOT NULL DEFAULT '',
+ip TEXT NOT NULL DEFAULT '',
+created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX ix_audit_events_entity ON audit_events(entity_type, entity_id, id);
+CREATE INDEX ix_audit_events_actor ON audit_events(actor, id);
+CREATE INDEX ix_audit_events_created_at ON audit_events(created_at, id);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS tg_freeze_completed_notes ON targets;
+DROP FUNCTION IF EXISTS freeze_completed_notes();
+DROP TABLE IF EXISTS target_note_revisions;
+DROP FUNCTION IF EXISTS reject_note_revision_update();
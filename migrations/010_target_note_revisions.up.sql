@@ -0,0 +1,48 @@
+-- Every value a target's notes have had, numbered per target; rows are
+-- only ever inserted
+CREATE TABLE target_note_revisions (
+id BIGSERIAL PRIMARY KEY,
+target_id BIGINT NOT NULL REFERENCES targets(id) ON DELETE CASCADE,
+revision INT NOT NULL CHECK (revision > 0),
+author TEXT NOT NULL DEFAULT '',
+notes TEXT NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX ux_target_note_revisions ON target_note_revisions(target_id, revision);
+
+
+CREATE OR REPLACE FUNCTION reject_note_revision_update()
+RETURNS trigger AS $$
+BEGIN
+RAISE EXCEPTION 'note revisions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+
+CREATE TRIGGER tg_note_revisions_immutable
+BEFORE UPDATE ON target_note_revisions
+FOR EACH ROW EXECUTE FUNCTION reject_note_revision_update();
+
+
+-- Notes freeze once the target is completed
+CREATE OR REPLACE FUNCTION freeze_completed_notes()
+RETURNS trigger AS $$
+BEGIN
+IF OLD.completed AND NEW.notes IS DISTINCT FROM OLD.notes THEN
+RAISE EXCEPTION 'target completed; notes frozen';
+END IF;
+RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+
+CREATE TRIGGER tg_freeze_completed_notes
+BEFORE UPDATE ON targets
+FOR EACH ROW EXECUTE FUNCTION freeze_completed_notes();
+
+
+-- Existing notes become the first revision of their target
+INSERT INTO target_note_revisions (target_id, revision, notes, created_at)
+SELECT id, 1, notes, updated_at
+FROM targets
+WHERE notes <> '';
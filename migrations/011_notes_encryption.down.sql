@@ -0,0 +1,22 @@
+-- Run `sca notes decrypt` first: notes sealed with the dropped data keys
+-- can no longer be read.
+DROP TABLE IF EXISTS mission_data_keys;
+
+
+CREATE OR REPLACE FUNCTION reject_note_revision_update()
+RETURNS trigger AS $$
+BEGIN
+RAISE EXCEPTION 'note revisions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+
+CREATE OR REPLACE FUNCTION freeze_completed_notes()
+RETURNS trigger AS $$
+BEGIN
+IF OLD.completed AND NEW.notes IS DISTINCT FROM OLD.notes THEN
+RAISE EXCEPTION 'target completed; notes frozen';
+END IF;
+RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,38 @@
+-- Per-mission data keys for target notes, wrapped by a master key that
+-- lives outside the database. Sealed notes name the data key they were
+-- encrypted with; a mission has several only during a rotation.
+CREATE TABLE mission_data_keys (
+id BIGSERIAL PRIMARY KEY,
+mission_id BIGINT NOT NULL REFERENCES missions(id) ON DELETE CASCADE,
+master_key_id TEXT NOT NULL,
+wrapped_key BYTEA NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX ix_mission_data_keys_mission ON mission_data_keys(mission_id, id);
+
+
+-- Re-encrypting notes (sca notes rotate) sets sca.rekeying for its
+-- transaction: it changes the stored form of frozen notes and of
+-- revisions without changing their content.
+CREATE OR REPLACE FUNCTION reject_note_revision_update()
+RETURNS trigger AS $$
+BEGIN
+IF current_setting('sca.rekeying', true) = 'on' AND NEW.notes IS DISTINCT FROM OLD.notes
+AND (NEW.id, NEW.target_id, NEW.revision, NEW.author, NEW.created_at) = (OLD.id, OLD.target_id, OLD.revision, OLD.author, OLD.created_at) THEN
+RETURN NEW;
+END IF;
+RAISE EXCEPTION 'note revisions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+
+CREATE OR REPLACE FUNCTION freeze_completed_notes()
+RETURNS trigger AS $$
+BEGIN
+IF OLD.completed AND NEW.notes IS DISTINCT FROM OLD.notes
+AND current_setting('sca.rekeying', true) IS DISTINCT FROM 'on' THEN
+RAISE EXCEPTION 'target completed; notes frozen';
+END IF;
+RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
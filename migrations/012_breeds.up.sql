@@ -0,0 +1,13 @@
+-- Local copy of the breed catalog, so validating a cat's breed needs no
+-- upstream call. source says where the row last came from: thecatapi, seed
+-- (the list bundled with the binary) or file. Breeds are never deleted by a
+-- sync; one that disappears upstream keeps its last synced_at.
+CREATE TABLE breeds (
+id TEXT PRIMARY KEY,
+name TEXT NOT NULL,
+source TEXT NOT NULL,
+synced_at TIMESTAMPTZ NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX ix_breeds_lower_name ON breeds(lower(name));
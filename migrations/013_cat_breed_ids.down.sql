@@ -0,0 +1,4 @@
+-- Cats keep their breed IDs; what was typed originally is gone.
+DROP INDEX IF EXISTS ix_cats_breed;
+ALTER TABLE cats DROP CONSTRAINT IF EXISTS cats_breed_fkey;
+ALTER TABLE breeds DROP COLUMN IF EXISTS origin;
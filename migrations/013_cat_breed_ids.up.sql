@@ -0,0 +1,108 @@
+-- Cats store the ID of their breed instead of whatever was typed, checked
+-- by a foreign key. Breeds also get their origin.
+ALTER TABLE breeds ADD COLUMN origin TEXT NOT NULL DEFAULT '';
+
+
+-- Existing cats are matched against the catalog, which may not have been
+-- synced yet: make sure it has at least the list bundled with the binary.
+INSERT INTO breeds (id, name, origin, source, synced_at) VALUES
+('abys', 'Abyssinian', 'Egypt', 'seed', now()),
+('aege', 'Aegean', 'Greece', 'seed', now()),
+('abob', 'American Bobtail', 'United States', 'seed', now()),
+('acur', 'American Curl', 'United States', 'seed', now()),
+('asho', 'American Shorthair', 'United States', 'seed', now()),
+('awir', 'American Wirehair', 'United States', 'seed', now()),
+('amau', 'Arabian Mau', 'United Arab Emirates', 'seed', now()),
+('amis', 'Australian Mist', 'Australia', 'seed', now()),
+('bali', 'Balinese', 'United States', 'seed', now()),
+('bamb', 'Bambino', 'United States', 'seed', now()),
+('beng', 'Bengal', 'United States', 'seed', now()),
+('birm', 'Birman', 'France', 'seed', now()),
+('bomb', 'Bombay', 'United States', 'seed', now()),
+('bslo', 'British Longhair', 'United Kingdom', 'seed', now()),
+('bsho', 'British Shorthair', 'United Kingdom', 'seed', now()),
+('bure', 'Burmese', 'Burma', 'seed', now()),
+('buri', 'Burmilla', 'United Kingdom', 'seed', now()),
+('cspa', 'California Spangled', 'United States', 'seed', now()),
+('ctif', 'Chantilly-Tiffany', 'United States', 'seed', now()),
+('char', 'Chartreux', 'France', 'seed', now()),
+('chau', 'Chausie', 'Egypt', 'seed', now()),
+('chee', 'Cheetoh', 'United States', 'seed', now()),
+('csho', 'Colorpoint Shorthair', 'United Kingdom', 'seed', now()),
+('crex', 'Cornish Rex', 'United Kingdom', 'seed', now()),
+('cymr', 'Cymric', 'Canada', 'seed', now()),
+('cypr', 'Cyprus', 'Cyprus', 'seed', now()),
+('drex', 'Devon Rex', 'United Kingdom', 'seed', now()),
+('dons', 'Donskoy', 'Russia', 'seed', now()),
+('lihu', 'Dragon Li', 'China', 'seed', now()),
+('emau', 'Egyptian Mau', 'Egypt', 'seed', now()),
+('ebur', 'European Burmese', 'Burma', 'seed', now()),
+('esho', 'Exotic Shorthair', 'United States', 'seed', now()),
+('hbro', 'Havana Brown', 'United Kingdom', 'seed', now()),
+('hima', 'Himalayan', 'United States', 'seed', now()),
+('jbob', 'Japanese Bobtail', 'Japan', 'seed', now()),
+('java', 'Javanese', 'United States', 'seed', now()),
+('khao', 'Khao Manee', 'Thailand', 'seed', now()),
+('kora', 'Korat', 'Thailand', 'seed', now()),
+('kuri', 'Kurilian', 'Russia', 'seed', now()),
+('lape', 'LaPerm', 'Thailand', 'seed', now()),
+('mcoo', 'Maine Coon', 'United States', 'seed', now()),
+('mala', 'Malayan', 'United Kingdom', 'seed', now()),
+('manx', 'Manx', 'Isle of Man', 'seed', now()),
+('munc', 'Munchkin', 'United States', 'seed', now()),
+('nebe', 'Nebelung', 'United States', 'seed', now()),
+('norw', 'Norwegian Forest Cat', 'Norway', 'seed', now()),
+('ocic', 'Ocicat', 'United States', 'seed', now()),
+('orie', 'Oriental', 'United States', 'seed', now()),
+('pers', 'Persian', 'Iran (Persia)', 'seed', now()),
+('pixi', 'Pixie-bob', 'United States', 'seed', now()),
+('raga', 'Ragamuffin', 'United States', 'seed', now()),
+('ragd', 'Ragdoll', 'United States', 'seed', now()),
+('rblu', 'Russian Blue', 'Russia', 'seed', now()),
+('sava', 'Savannah', 'United States', 'seed', now()),
+('sfol', 'Scottish Fold', 'United Kingdom', 'seed', now()),
+('srex', 'Selkirk Rex', 'United States', 'seed', now()),
+('siam', 'Siamese', 'Thailand', 'seed', now()),
+('sibe', 'Siberian', 'Russia', 'seed', now()),
+('sing', 'Singapura', 'Singapore', 'seed', now()),
+('snow', 'Snowshoe', 'United States', 'seed', now()),
+('soma', 'Somali', 'Somalia', 'seed', now()),
+('sphy', 'Sphynx', 'Canada', 'seed', now()),
+('tonk', 'Tonkinese', 'Canada', 'seed', now()),
+('toyg', 'Toyger', 'United States', 'seed', now()),
+('tang', 'Turkish Angora', 'Turkey', 'seed', now()),
+('tvan', 'Turkish Van', 'Turkey', 'seed', now()),
+('ycho', 'York Chocolate', 'United States', 'seed', now())
+ON CONFLICT (id) DO UPDATE SET origin = EXCLUDED.origin WHERE breeds.origin = '';
+
+
+-- Resolve by ID first, then by name, ignoring case and spaces.
+UPDATE cats SET breed = b.id
+FROM breeds b
+WHERE lower(btrim(cats.breed)) = lower(b.id) AND cats.breed <> b.id;
+
+
+UPDATE cats SET breed = b.id
+FROM breeds b
+WHERE lower(btrim(cats.breed)) = lower(b.name)
+AND NOT EXISTS (SELECT 1 FROM breeds WHERE breeds.id = cats.breed);
+
+
+-- Stop with the list of cats that still don't resolve, so they can be fixed
+-- by hand before migrating again.
+DO $$
+DECLARE unresolved TEXT;
+BEGIN
+SELECT string_agg(format('cat %s (%L)', id, breed), ', ' ORDER BY id) INTO unresolved
+FROM cats
+WHERE NOT EXISTS (SELECT 1 FROM breeds WHERE breeds.id = cats.breed);
+IF unresolved IS NOT NULL THEN
+RAISE EXCEPTION 'cats with breeds not in the catalog: %', unresolved
+USING HINT = 'Set them to a breed ID (UPDATE cats SET breed = ''siam'' WHERE id = ...) or add the breeds, then migrate again.';
+END IF;
+END
+$$;
+
+
+ALTER TABLE cats ADD CONSTRAINT cats_breed_fkey FOREIGN KEY (breed) REFERENCES breeds(id);
+CREATE INDEX ix_cats_breed ON cats(breed);
@@ -0,0 +1,10 @@
+ALTER TABLE breeds
+DROP COLUMN IF EXISTS alt_names,
+DROP COLUMN IF EXISTS country_code,
+DROP COLUMN IF EXISTS description,
+DROP COLUMN IF EXISTS temperament,
+DROP COLUMN IF EXISTS life_span,
+DROP COLUMN IF EXISTS weight_imperial,
+DROP COLUMN IF EXISTS weight_metric,
+DROP COLUMN IF EXISTS traits,
+DROP COLUMN IF EXISTS wikipedia_url;
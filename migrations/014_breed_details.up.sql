@@ -0,0 +1,13 @@
+-- The rest of TheCatAPI's breed record: descriptive fields, weight and life
+-- span ranges, and the trait ratings as one JSON object. Rows get them on
+-- their next sync.
+ALTER TABLE breeds
+ADD COLUMN alt_names TEXT NOT NULL DEFAULT '',
+ADD COLUMN country_code TEXT NOT NULL DEFAULT '',
+ADD COLUMN description TEXT NOT NULL DEFAULT '',
+ADD COLUMN temperament TEXT NOT NULL DEFAULT '',
+ADD COLUMN life_span TEXT NOT NULL DEFAULT '',
+ADD COLUMN weight_imperial TEXT NOT NULL DEFAULT '',
+ADD COLUMN weight_metric TEXT NOT NULL DEFAULT '',
+ADD COLUMN traits JSONB NOT NULL DEFAULT '{}',
+ADD COLUMN wikipedia_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+// Package migrations bundles the SQL migrations into the binary so the
+// service can migrate without the directory being present on disk.
+package migrations
+
+import "embed"
+
+// FS holds every NNN_name.up.sql / NNN_name.down.sql pair in this directory.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"sca/sca/internal/auth"
+	"sca/sca/internal/models"
+	"sca/sca/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+const apikeyUsage = "usage: sca apikey create [--role handler|cat|finance] [--cat ID] NAME | list | revoke ID"
+
+// runAPIKey implements `sca apikey create|list|revoke`.
+func runAPIKey(db *gorm.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(apikeyUsage)
+	}
+	keys := repository.NewPostgres(db).APIKeys()
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		role := fs.String("role", string(models.RoleHandler), "role of the key: handler, cat or finance")
+		catID := fs.Uint("cat", 0, "cat the key acts for (required with --role cat)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return errors.New(apikeyUsage)
+		}
+		if fs.NArg() != 1 || fs.Arg(0) == "" {
+			return errors.New(apikeyUsage)
+		}
+		r := models.Role(*role)
+		switch {
+		case !r.Valid():
+			return fmt.Errorf("unknown role %q", *role)
+		case r == models.RoleCat && *catID == 0:
+			return errors.New("--role cat needs --cat")
+		case r != models.RoleCat && *catID != 0:
+			return errors.New("--cat only applies to --role cat")
+		}
+
+		plaintext, k := auth.GenerateKey(fs.Arg(0), r)
+		if *catID != 0 {
+			id := *catID
+			k.CatID = &id
+		}
+		if err := keys.Create(ctx, k); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "created %s key %d (%s); store it now, it cannot be shown again\n", k.Role, k.ID, k.Name)
+		fmt.Println(plaintext)
+	case "list":
+		list, err := keys.List(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tROLE\tPREFIX\tCREATED AT\tLAST USED\tREVOKED AT")
+		for _, k := range list {
+			role := string(k.Role)
+			if k.CatID != nil {
+				role += fmt.Sprintf(" (cat %d)", *k.CatID)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, role, k.Prefix,
+				k.CreatedAt.Format(time.RFC3339), orDash(k.LastUsedAt), orDash(k.RevokedAt))
+		}
+		return w.Flush()
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(apikeyUsage)
+		}
+		id, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil || id == 0 {
+			return fmt.Errorf("invalid key id %q", args[1])
+		}
+		if err := keys.Revoke(ctx, uint(id), time.Now()); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return fmt.Errorf("no active key with id %d", id)
+			}
+			return err
+		}
+		fmt.Printf("key %d revoked\n", id)
+	default:
+		return errors.New(apikeyUsage)
+	}
+	return nil
+}
+
+func orDash(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"sca/sca/internal/breeds"
+	"sca/sca/internal/clients/thecatapi"
+	"sca/sca/internal/config"
+	"sca/sca/internal/models"
+	"sca/sca/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+const breedsUsage = "usage: sca breeds sync [--offline | --file PATH]"
+
+// runBreeds implements `sca breeds sync`, which fills the breed catalog
+// from TheCatAPI, the bundled seed (--offline) or a JSON file in
+// TheCatAPI's /breeds format (--file).
+func runBreeds(cfg *config.Config, db *gorm.DB, args []string) error {
+	if len(args) == 0 || args[0] != "sync" {
+		return errors.New(breedsUsage)
+	}
+	fs := flag.NewFlagSet("breeds sync", flag.ContinueOnError)
+	offline := fs.Bool("offline", false, "load the breed list bundled with sca instead of calling TheCatAPI")
+	file := fs.String("file", "", "load breeds from this JSON file instead of calling TheCatAPI")
+	if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 || (*offline && *file != "") {
+		return errors.New(breedsUsage)
+	}
+
+	syncer := breeds.NewSyncer(repository.NewPostgres(db).Breeds(), thecatapi.NewHTTP(cfg.TheCatAPI))
+	ctx := context.Background()
+	var (
+		n      int
+		err    error
+		source string
+	)
+	switch {
+	case *offline:
+		source = models.BreedSourceSeed
+		n, err = syncer.Seed(ctx)
+	case *file != "":
+		source = models.BreedSourceFile
+		f, openErr := os.Open(*file)
+		if openErr != nil {
+			return openErr
+		}
+		defer f.Close()
+		n, err = syncer.Load(ctx, f, source)
+	default:
+		source = models.BreedSourceTheCatAPI
+		n, err = syncer.Sync(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d breeds synced from %s\n", n, source)
+	return nil
+}
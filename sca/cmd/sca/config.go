@@ -0,0 +1,18 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"sca/sca/internal/config"
+)
+
+const configUsage = "usage: sca config print"
+
+// runConfig implements `sca config print`.
+func runConfig(cfg *config.Config, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return errors.New(configUsage)
+	}
+	return cfg.Print(os.Stdout)
+}
@@ -1,11 +1,20 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"log"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"sca/sca/internal/config"
+	"sca/sca/internal/logging"
 	"sca/sca/internal/server"
 	"sca/sca/internal/storage"
+	"sca/sca/internal/tracing"
 )
 
 // @title Spy Cat Agency APIgo mod vendor
@@ -13,18 +22,106 @@ import (
 // @description CRUD API for Spy Cats, Missions and Targets.
 // @BasePath /api/v1
 // @schemes http
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description API key or JWT as "Bearer <token>"
 
 func main() {
-	migrateOnly := flag.Bool("migrate-only", false, "run migrations and exit")
-	flag.Parse()
+	migrateOnly := flag.Bool("migrate-only", false, "run migrations and exit (same as `migrate up`)")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.Log.Format, cfg.Log.SlogLevel())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "config" {
+		if err := runConfig(cfg, args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	flushTraces, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		slog.Error("tracing setup failed", "err", err)
+		os.Exit(2)
+	}
 
-	db := storage.MustInitDBFromEnv()
+	db := storage.MustInitDB(cfg.Database)
+	migrations := storage.MigrationsFS(cfg.Database.MigrationsDir)
 	if *migrateOnly {
-		storage.MustRunMigrations(db)
+		storage.MustRunMigrations(db, migrations)
 		return
 	}
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			if err := runMigrate(db, migrations, args[1:]); err != nil {
+				slog.Error("migrate failed", "err", err)
+				os.Exit(1)
+			}
+			return
+		case "apikey":
+			if err := runAPIKey(db, args[1:]); err != nil {
+				slog.Error("apikey failed", "err", err)
+				os.Exit(1)
+			}
+			return
+		case "breeds":
+			if err := runBreeds(cfg, db, args[1:]); err != nil {
+				slog.Error("breeds failed", "err", err)
+				os.Exit(1)
+			}
+			return
+		case "notes":
+			if err := runNotes(cfg, db, args[1:]); err != nil {
+				slog.Error("notes failed", "err", err)
+				os.Exit(1)
+			}
+			return
+		default:
+			slog.Error("unknown command", "command", args[0])
+			os.Exit(2)
+		}
+	}
+
+	storage.MustRunMigrations(db, migrations)
 
-	r := server.Router(db)
-	log.Println("listening on :8080")
-	r.Run(":8080")
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop() // a second signal kills the process without waiting for the drain
+	}()
+
+	srv, err := server.New(cfg, db)
+	if err != nil {
+		slog.Error("server setup failed", "err", err)
+		os.Exit(1)
+	}
+	runErr := srv.Run(ctx)
+	if err := storage.Close(db); err != nil {
+		slog.Error("closing database", "err", err)
+	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := flushTraces(flushCtx); err != nil {
+		slog.Error("flushing traces", "err", err)
+	}
+	if runErr != nil {
+		slog.Error("server failed", "err", runErr)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"sca/sca/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+const migrateUsage = "usage: sca migrate up | down [N] | status | redo"
+
+// runMigrate implements `sca migrate up|down|status|redo`.
+func runMigrate(db *gorm.DB, migrations fs.FS, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	m, err := storage.NewMigrator(db, migrations)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migration(s) applied\n", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+		}
+		n, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migration(s) reverted\n", n)
+	case "redo":
+		return m.Redo(ctx)
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range list {
+			state, at := "pending", "-"
+			if s.Applied {
+				state, at = "applied", s.AppliedAt.Format("2006-01-02 15:04:05Z07:00")
+			}
+			if s.ChecksumMismatch {
+				state = "modified"
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", s.Version, s.Name, state, at)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
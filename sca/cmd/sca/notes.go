@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"sca/sca/internal/config"
+	"sca/sca/internal/encryption"
+	"sca/sca/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+const notesUsage = "usage: sca notes encrypt|rotate|decrypt [--batch N]"
+
+// runNotes implements `sca notes encrypt|rotate|decrypt`, which rewrite
+// target notes mission by mission, --batch missions per transaction.
+func runNotes(cfg *config.Config, db *gorm.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(notesUsage)
+	}
+	var mode repository.RekeyMode
+	switch args[0] {
+	case "encrypt":
+		mode = repository.RekeyPlaintext
+	case "rotate":
+		mode = repository.RekeyRotate
+	case "decrypt":
+		mode = repository.RekeyDecrypt
+	default:
+		return errors.New(notesUsage)
+	}
+	fs := flag.NewFlagSet("notes "+args[0], flag.ContinueOnError)
+	batch := fs.Int("batch", 100, "missions rewritten per transaction")
+	if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 || *batch < 1 {
+		return errors.New(notesUsage)
+	}
+
+	keys, err := encryption.ParseKeyring(cfg.Encryption.Keys)
+	if err != nil {
+		return fmt.Errorf("encryption.keys: %w", err)
+	}
+	if keys == nil && mode != repository.RekeyDecrypt {
+		return errors.New("no encryption keys configured; set ENCRYPTION_KEYS or ENCRYPTION_KEYS_FILE")
+	}
+	if keys != nil {
+		fmt.Fprintf(os.Stderr, "current master key %s\n", keys.CurrentID())
+	}
+	store := repository.NewPostgres(db, repository.WithNotesKeys(keys))
+	ctx := context.Background()
+
+	var after uint
+	total := 0
+	for {
+		last, n, err := store.Rekey(ctx, mode, after, *batch)
+		if err != nil {
+			return fmt.Errorf("after mission %d: %w", after, err)
+		}
+		if last == 0 {
+			break
+		}
+		total += n
+		fmt.Fprintf(os.Stderr, "missions up to %d: %d notes rewritten\n", last, n)
+		after = last
+	}
+	fmt.Printf("%d notes rewritten\n", total)
+	return nil
+}
@@ -0,0 +1,232 @@
+// Package assignment puts cats on missions, takes them off and hands
+// missions over to other cats. Every operation runs in one transaction that
+// locks the mission and the incoming cat, so the one-active-mission-per-cat
+// rule is checked against settled data, and records the state transition,
+// the hand-over history and the audit event alongside the change.
+package assignment
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"sca/sca/internal/audit"
+	"sca/sca/internal/models"
+	"sca/sca/internal/notes"
+	"sca/sca/internal/repository"
+)
+
+// ErrNotAssigned is returned when removing or swapping the cat of a mission
+// that has none.
+var ErrNotAssigned = errors.New("mission has no assigned cat")
+
+// createReason is recorded when a mission is created with a cat and the
+// request gives no reason of its own.
+const createReason = "assigned on creation"
+
+// Change says who changes an assignment and why. Actor is the
+// authenticated principal, empty when authentication is disabled; Reason
+// is optional.
+type Change struct {
+	Actor  string
+	Reason string
+}
+
+type Service struct {
+	store repository.Store
+}
+
+func New(store repository.Store) *Service { return &Service{store: store} }
+
+// Create inserts m with its targets. A mission with AssignedCatID starts
+// assigned, otherwise it starts as draft.
+func (sv *Service) Create(ctx context.Context, m *models.Mission, ch Change) error {
+	return sv.store.Tx(ctx, func(s repository.Store) error {
+		now := time.Now()
+		catID := m.AssignedCatID
+		m.AssignedCatID, m.State, m.StateChangedAt = nil, models.StateDraft, now
+		if catID == nil {
+			if err := s.Missions().Create(ctx, m); err != nil {
+				return err
+			}
+			return recordCreate(ctx, s, m, ch.Actor)
+		}
+
+		if err := claim(ctx, s, *catID); err != nil {
+			return err
+		}
+		if ch.Reason == "" {
+			ch.Reason = createReason
+		}
+		m.AssignedCatID = catID
+		tr, err := m.Transition(models.StateAssigned, ch.Reason, now)
+		if err != nil {
+			return err
+		}
+		if err := s.Missions().Create(ctx, m); err != nil {
+			return err
+		}
+		tr.MissionID = m.ID
+		if err := s.Missions().AddTransition(ctx, &tr); err != nil {
+			return err
+		}
+		if err := record(ctx, s, m, nil, ch, now); err != nil {
+			return err
+		}
+		return recordCreate(ctx, s, m, ch.Actor)
+	})
+}
+
+// recordCreate audits the creation of m and each of its targets and starts
+// the notes history of targets created with notes.
+func recordCreate(ctx context.Context, s repository.Store, m *models.Mission, actor string) error {
+	if err := audit.Record(ctx, s, audit.ActionCreate, audit.EntityMission, m.ID, nil, m); err != nil {
+		return err
+	}
+	for i := range m.Targets {
+		t := &m.Targets[i]
+		if err := audit.Record(ctx, s, audit.ActionCreate, audit.EntityTarget, t.ID, nil, t); err != nil {
+			return err
+		}
+		if err := notes.Record(ctx, s, t, "", actor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Assign puts the cat on a draft mission, moving it to assigned. A mission
+// that already has a cat fails with repository.ErrNotEditable; use Reassign.
+func (sv *Service) Assign(ctx context.Context, missionID, catID uint, ch Change) (*models.Mission, error) {
+	var m *models.Mission
+	err := sv.store.Tx(ctx, func(s repository.Store) error {
+		var err error
+		if m, err = lock(ctx, s, missionID); err != nil {
+			return err
+		}
+		if m.AssignedCatID != nil {
+			return repository.ErrNotEditable
+		}
+		if err := claim(ctx, s, catID); err != nil {
+			return err
+		}
+		before := *m
+		m.AssignedCatID = &catID
+		if err := move(ctx, s, m, models.StateAssigned, nil, ch, time.Now()); err != nil {
+			return err
+		}
+		return audit.Record(ctx, s, audit.ActionAssign, audit.EntityMission, m.ID, &before, m)
+	})
+	return m, err
+}
+
+// Reassign hands an assigned or active mission over to another cat; the
+// mission keeps its state.
+func (sv *Service) Reassign(ctx context.Context, missionID, catID uint, ch Change) (*models.Mission, error) {
+	var m *models.Mission
+	err := sv.store.Tx(ctx, func(s repository.Store) error {
+		var err error
+		if m, err = lock(ctx, s, missionID); err != nil {
+			return err
+		}
+		from := m.AssignedCatID
+		if from == nil {
+			return ErrNotAssigned
+		}
+		if err := claim(ctx, s, catID); err != nil {
+			return err
+		}
+		before := *m
+		m.AssignedCatID = &catID
+		if err := s.Missions().Update(ctx, m); err != nil {
+			return err
+		}
+		if err := record(ctx, s, m, from, ch, m.UpdatedAt); err != nil {
+			return err
+		}
+		return audit.Record(ctx, s, audit.ActionReassign, audit.EntityMission, m.ID, &before, m)
+	})
+	return m, err
+}
+
+// Unassign takes the cat off an assigned mission, which returns to draft.
+// Active missions can't lose their cat (models.ErrInvalidTransition).
+func (sv *Service) Unassign(ctx context.Context, missionID uint, ch Change) (*models.Mission, error) {
+	var m *models.Mission
+	err := sv.store.Tx(ctx, func(s repository.Store) error {
+		var err error
+		if m, err = lock(ctx, s, missionID); err != nil {
+			return err
+		}
+		from := m.AssignedCatID
+		if from == nil {
+			return ErrNotAssigned
+		}
+		before := *m
+		m.AssignedCatID = nil
+		if err := move(ctx, s, m, models.StateDraft, from, ch, time.Now()); err != nil {
+			return err
+		}
+		return audit.Record(ctx, s, audit.ActionUnassign, audit.EntityMission, m.ID, &before, m)
+	})
+	return m, err
+}
+
+// lock loads and locks an open mission.
+func lock(ctx context.Context, s repository.Store, missionID uint) (*models.Mission, error) {
+	m, err := s.Missions().GetForUpdate(ctx, missionID)
+	if err != nil {
+		return nil, err
+	}
+	if m.State.Terminal() {
+		return nil, &models.ClosedError{State: m.State}
+	}
+	return m, nil
+}
+
+// claim locks the cat's row, so concurrent assignments of the same cat
+// queue up behind each other, and checks that no mission holds it. A cat
+// held by the very mission being changed is busy as well.
+func claim(ctx context.Context, s repository.Store, catID uint) error {
+	if _, err := s.Cats().GetForUpdate(ctx, catID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return repository.ErrCatNotFound
+		}
+		return err
+	}
+	held, err := s.Missions().HeldBy(ctx, catID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return nil
+	case err != nil:
+		return err
+	}
+	return &repository.CatBusyError{CatID: catID, MissionID: held.ID}
+}
+
+// move transitions m to state to, saves it and records the transition and
+// the hand-over from cat from.
+func move(ctx context.Context, s repository.Store, m *models.Mission, to models.MissionState, from *uint, ch Change, at time.Time) error {
+	tr, err := m.Transition(to, ch.Reason, at)
+	if err != nil {
+		return err
+	}
+	if err := s.Missions().Update(ctx, m); err != nil {
+		return err
+	}
+	if err := s.Missions().AddTransition(ctx, &tr); err != nil {
+		return err
+	}
+	return record(ctx, s, m, from, ch, at)
+}
+
+func record(ctx context.Context, s repository.Store, m *models.Mission, from *uint, ch Change, at time.Time) error {
+	return s.Missions().AddAssignment(ctx, &models.MissionAssignment{
+		MissionID: m.ID,
+		FromCatID: from,
+		ToCatID:   m.AssignedCatID,
+		Actor:     ch.Actor,
+		Reason:    ch.Reason,
+		CreatedAt: at,
+	})
+}
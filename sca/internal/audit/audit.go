@@ -0,0 +1,138 @@
+// Package audit records who changed what. Mutations call Record with the
+// entity before and after the change, through the Store of their own
+// transaction, so an event exists exactly when the change was committed.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"sca/sca/internal/models"
+	"sca/sca/internal/repository"
+)
+
+// Entity types.
+const (
+	EntityCat     = "cat"
+	EntityMission = "mission"
+	EntityTarget  = "target"
+)
+
+// Actions.
+const (
+	ActionCreate     = "create"
+	ActionUpdate     = "update"
+	ActionDelete     = "delete"
+	ActionTransition = "transition"
+	ActionAssign     = "assign"
+	ActionUnassign   = "unassign"
+	ActionReassign   = "reassign"
+)
+
+// Source says where a change came from.
+type Source struct {
+	Actor     string
+	RequestID string
+	IP        string
+}
+
+type sourceKey struct{}
+
+// NewContext returns a copy of ctx whose changes are attributed to src.
+func NewContext(ctx context.Context, src Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, src)
+}
+
+// SourceFrom returns the source in ctx; changes made outside a request
+// have an empty one.
+func SourceFrom(ctx context.Context) Source {
+	src, _ := ctx.Value(sourceKey{}).(Source)
+	return src
+}
+
+// ignored fields never show up in changes: id is the event's entity_id,
+// updated_at moves on every write, and targets are audited as entities of
+// their own.
+var ignored = []string{"id", "updated_at", "targets"}
+
+// redacted fields are reported as changed without their values, keeping
+// field reports out of the audit trail.
+var redacted = []string{"notes"}
+
+var redactedValue = json.RawMessage(`"[REDACTED]"`)
+
+// Record writes an event for entity id changing from before to after
+// through s. before is nil for creations and after for deletions. Updates
+// that changed nothing are not recorded.
+func Record(ctx context.Context, s repository.Store, action, entity string, id uint, before, after any) error {
+	changes, err := Diff(before, after)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 && action == ActionUpdate {
+		return nil
+	}
+	src := SourceFrom(ctx)
+	return s.Audit().Record(ctx, &models.AuditEvent{
+		Actor:      src.Actor,
+		Action:     action,
+		EntityType: entity,
+		EntityID:   id,
+		Changes:    changes,
+		RequestID:  src.RequestID,
+		IP:         src.IP,
+	})
+}
+
+// Diff compares the JSON forms of before and after field by field; either
+// may be nil.
+func Diff(before, after any) (models.AuditChanges, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := models.AuditChanges{}
+	for k := range merge(a, b) {
+		if slices.Contains(ignored, k) || string(b[k]) == string(a[k]) {
+			continue
+		}
+		ch := models.FieldChange{Before: b[k], After: a[k]}
+		if slices.Contains(redacted, k) {
+			if ch.Before != nil {
+				ch.Before = redactedValue
+			}
+			if ch.After != nil {
+				ch.After = redactedValue
+			}
+		}
+		changes[k] = ch
+	}
+	return changes, nil
+}
+
+func fields(v any) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	return m, json.Unmarshal(raw, &m)
+}
+
+func merge(a, b map[string]json.RawMessage) map[string]json.RawMessage {
+	out := maps.Clone(a)
+	if out == nil {
+		out = map[string]json.RawMessage{}
+	}
+	maps.Copy(out, b)
+	return out
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"sca/sca/internal/models"
+)
+
+// keyPrefix starts every API key, so keys are recognisable in config
+// files and secret scanners, and distinguishable from JWTs.
+const keyPrefix = "sca_"
+
+// GenerateKey creates a key named name with the given role. The returned
+// plaintext has the form sca_<prefix>_<secret> and is shown once; only its
+// hash is stored.
+func GenerateKey(name string, role models.Role) (plaintext string, key *models.APIKey) {
+	p := make([]byte, 6)
+	s := make([]byte, 32)
+	rand.Read(p)
+	rand.Read(s)
+	prefix := hex.EncodeToString(p)
+	plaintext = keyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(s)
+	return plaintext, &models.APIKey{Name: name, Prefix: prefix, Hash: hashKey(plaintext), Role: role}
+}
+
+// IsAPIKey reports whether s looks like a key made by GenerateKey.
+func IsAPIKey(s string) bool { return strings.HasPrefix(s, keyPrefix) }
+
+// splitKey returns the public prefix of plaintext.
+func splitKey(plaintext string) (prefix string, ok bool) {
+	rest, ok := strings.CutPrefix(plaintext, keyPrefix)
+	if !ok {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+func hashKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// matchKey reports whether plaintext is the key k was generated from.
+func matchKey(k *models.APIKey, plaintext string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashKey(plaintext)), []byte(k.Hash)) == 1
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+
+	"sca/sca/internal/config"
+	"sca/sca/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTVerifier checks bearer tokens: HS256 against the shared secret and
+// RS256 against the RSA keys of a local JWKS file.
+type JWTVerifier struct {
+	secret []byte
+	keys   map[string]*rsa.PublicKey // by kid
+	parser *jwt.Parser
+}
+
+// NewJWTVerifier builds a verifier from cfg. It returns nil when neither a
+// secret nor a JWKS file is configured, in which case tokens are refused.
+func NewJWTVerifier(cfg config.Auth) (*JWTVerifier, error) {
+	v := &JWTVerifier{}
+	var methods []string
+	if cfg.JWTSecret != "" {
+		v.secret = []byte(cfg.JWTSecret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, nil
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+	return v, nil
+}
+
+// Claims are the claims of a bearer token. Roles not known to this
+// service are ignored, so tokens of a shared identity provider may carry
+// others.
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []models.Role `json:"roles"`
+	// CatID binds the cat role to a cat.
+	CatID uint `json:"cat_id"`
+}
+
+// Verify parses token and checks its signature and claims.
+func (v *JWTVerifier) Verify(token string) (*Claims, error) {
+	var claims Claims
+	if _, err := v.parser.ParseWithClaims(token, &claims, v.key); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no sub claim")
+	}
+	known := claims.Roles[:0]
+	for _, r := range claims.Roles {
+		if r.Valid() {
+			known = append(known, r)
+		}
+	}
+	claims.Roles = known
+	if slices.Contains(claims.Roles, models.RoleCat) && claims.CatID == 0 {
+		return nil, errors.New("cat role requires a cat_id claim")
+	}
+	return &claims, nil
+}
+
+func (v *JWTVerifier) key(t *jwt.Token) (any, error) {
+	switch t.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := t.Header["kid"].(string)
+		if k, ok := v.keys[kid]; ok {
+			return k, nil
+		}
+		// A token without kid is fine as long as the set is unambiguous.
+		if kid == "" && len(v.keys) == 1 {
+			for _, k := range v.keys {
+				return k, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// loadJWKS reads the RSA signing keys of a JWKS file. Keys of other types
+// or marked for encryption are skipped.
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	var set jwks
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("jwks %s: %w", path, err)
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for i, k := range set.Keys {
+		if k.Kty != "RSA" || k.Use == "enc" || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwks %s: key %d: bad modulus: %w", path, i, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("jwks %s: key %d: bad exponent", path, i)
+		}
+		if _, dup := keys[k.Kid]; dup {
+			return nil, fmt.Errorf("jwks %s: duplicate kid %q", path, k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks %s: no RSA signing keys", path)
+	}
+	return keys, nil
+}
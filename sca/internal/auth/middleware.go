@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"sca/sca/internal/logging"
+	"sca/sca/internal/models"
+	"sca/sca/internal/problem"
+	"sca/sca/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// APIKeyHeader is the alternative to Authorization: Bearer for API keys.
+const APIKeyHeader = "X-API-Key"
+
+// touchInterval limits how often last_used_at is written for a busy key.
+const touchInterval = time.Minute
+
+// credentialError rejects the credentials a caller presented, as opposed
+// to failing to check them.
+type credentialError struct{ msg string }
+
+func (e *credentialError) Error() string { return e.msg }
+
+var (
+	errNoCredentials  = &credentialError{"missing API key or bearer token"}
+	errInvalidKey     = &credentialError{"invalid API key"}
+	errRevokedKey     = &credentialError{"API key revoked"}
+	errTokensDisabled = &credentialError{"bearer tokens are not accepted"}
+)
+
+// Authenticator resolves the credentials of a request to a Principal.
+type Authenticator struct {
+	keys repository.APIKeyRepository
+	jwt  *JWTVerifier // nil when tokens are not configured
+	now  func() time.Time
+}
+
+func NewAuthenticator(keys repository.APIKeyRepository, jwt *JWTVerifier) *Authenticator {
+	return &Authenticator{keys: keys, jwt: jwt, now: time.Now}
+}
+
+// Middleware rejects requests without valid credentials with a 401
+// UNAUTHENTICATED problem. Authenticated requests carry the principal in
+// their context (see FromContext), and their logger and span name it.
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		p, err := a.Authenticate(ctx, c.Request)
+		var cerr *credentialError
+		switch {
+		case errors.As(err, &cerr):
+			logging.From(ctx).Info("authentication failed", "err", err)
+			c.Header("WWW-Authenticate", `Bearer realm="sca"`)
+			problem.Write(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthenticated, cerr.msg))
+			return
+		case err != nil:
+			problem.Error(c, err)
+			return
+		}
+
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("enduser.id", p.Subject))
+		ctx = logging.NewContext(ctx, logging.From(ctx).With("principal", p.Subject))
+		c.Request = c.Request.WithContext(NewContext(ctx, p))
+		c.Next()
+	}
+}
+
+// Authenticate checks the API key (X-API-Key, or a bearer value starting
+// with sca_) or the bearer JWT of r. Credentials that do not check out
+// are a *credentialError; any other error means they could not be checked.
+func (a *Authenticator) Authenticate(ctx context.Context, r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.apiKey(ctx, key)
+	}
+	scheme, cred, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(cred) == "" {
+		return nil, errNoCredentials
+	}
+	cred = strings.TrimSpace(cred)
+	if IsAPIKey(cred) {
+		return a.apiKey(ctx, cred)
+	}
+	if a.jwt == nil {
+		return nil, errTokensDisabled
+	}
+	claims, err := a.jwt.Verify(cred)
+	if err != nil {
+		return nil, &credentialError{"invalid bearer token: " + err.Error()}
+	}
+	return &Principal{Subject: claims.Subject, Method: MethodJWT, Roles: claims.Roles, CatID: claims.CatID}, nil
+}
+
+func (a *Authenticator) apiKey(ctx context.Context, plaintext string) (*Principal, error) {
+	prefix, ok := splitKey(plaintext)
+	if !ok {
+		return nil, errInvalidKey
+	}
+	k, err := a.keys.GetByPrefix(ctx, prefix)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, errInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !matchKey(k, plaintext) {
+		return nil, errInvalidKey
+	}
+	if k.RevokedAt != nil {
+		return nil, errRevokedKey
+	}
+
+	now := a.now()
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= touchInterval {
+		// Losing a usage timestamp is not worth failing the request.
+		if err := a.keys.Touch(ctx, k.ID, now); err != nil {
+			logging.From(ctx).Warn("recording API key use", "key_id", k.ID, "err", err)
+		}
+	}
+	p := &Principal{Subject: "apikey:" + k.Name, Method: MethodAPIKey, KeyID: k.ID, Roles: []models.Role{k.Role}}
+	if k.CatID != nil {
+		p.CatID = *k.CatID
+	}
+	return p, nil
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"sca/sca/internal/models"
+	"sca/sca/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permission allows one kind of operation. Routes require permissions,
+// roles grant them.
+type Permission string
+
+const (
+	PermCatsRead   Permission = "cats:read"
+	PermCatsWrite  Permission = "cats:write"
+	PermCatsSalary Permission = "cats:salary"
+	PermBreedsRead Permission = "breeds:read"
+	// PermMissionsRead covers every mission, PermMissionsReadOwn only
+	// those assigned to the principal's cat.
+	PermMissionsRead    Permission = "missions:read"
+	PermMissionsReadOwn Permission = "missions:read:own"
+	PermMissionsWrite   Permission = "missions:write"
+	// PermTargetsWrite covers the targets of every mission,
+	// PermTargetsWriteOwn only those of the principal's active mission.
+	PermTargetsWrite    Permission = "targets:write"
+	PermTargetsWriteOwn Permission = "targets:write:own"
+	PermAuditRead       Permission = "audit:read"
+)
+
+var rolePermissions = map[models.Role][]Permission{
+	models.RoleHandler: {
+		PermCatsRead, PermCatsWrite, PermBreedsRead,
+		PermMissionsRead, PermMissionsWrite, PermTargetsWrite, PermAuditRead,
+	},
+	models.RoleCat: {
+		PermMissionsReadOwn, PermTargetsWriteOwn,
+	},
+	models.RoleFinance: {
+		PermCatsRead, PermCatsSalary, PermBreedsRead, PermAuditRead,
+	},
+}
+
+// Can reports whether one of p's roles grants perm.
+func (p *Principal) Can(perm Permission) bool {
+	for _, r := range p.Roles {
+		if slices.Contains(rolePermissions[r], perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether the principal in ctx has perm. Without a principal
+// authentication is disabled and everything is allowed.
+func Can(ctx context.Context, perm Permission) bool {
+	p := FromContext(ctx)
+	return p == nil || p.Can(perm)
+}
+
+// OwnCat returns the cat whose missions the principal in ctx is limited
+// to, and false when it may see every mission. A principal limited to
+// missions without a cat of its own gets 0, which matches no mission.
+func OwnCat(ctx context.Context) (uint, bool) {
+	p := FromContext(ctx)
+	if p == nil || p.Can(PermMissionsRead) {
+		return 0, false
+	}
+	return p.CatID, true
+}
+
+// Forbidden is the problem for an authenticated caller lacking a
+// permission.
+func Forbidden(detail string) *problem.Problem {
+	return problem.New(http.StatusForbidden, problem.CodeForbidden, detail)
+}
+
+// Require lets the request through when the principal has any of perms,
+// and answers 403 FORBIDDEN otherwise.
+func Require(perms ...Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, perm := range perms {
+			if Can(c.Request.Context(), perm) {
+				c.Next()
+				return
+			}
+		}
+		problem.Write(c, Forbidden("missing permission "+string(perms[0])))
+	}
+}
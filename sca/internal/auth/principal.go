@@ -0,0 +1,54 @@
+// Package auth authenticates API callers with static API keys or JWT
+// bearer tokens, carries the resulting Principal through the request and
+// decides what its roles permit.
+package auth
+
+import (
+	"context"
+
+	"sca/sca/internal/models"
+)
+
+// Method says how a principal authenticated.
+type Method string
+
+const (
+	MethodAPIKey Method = "api_key"
+	MethodJWT    Method = "jwt"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject identifies the caller: the sub claim of a token, or
+	// "apikey:" followed by the key's name.
+	Subject string `json:"subject"`
+	Method  Method `json:"method"`
+	// KeyID is the api_keys row the caller used; zero for tokens.
+	KeyID uint          `json:"key_id,omitempty"`
+	Roles []models.Role `json:"roles"`
+	// CatID is the cat a principal with the cat role acts for.
+	CatID uint `json:"cat_id,omitempty"`
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying p.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal in ctx, or nil for unauthenticated
+// requests and background work.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Subject returns the subject of the principal in ctx, or "" when there
+// is none.
+func Subject(ctx context.Context) string {
+	if p := FromContext(ctx); p != nil {
+		return p.Subject
+	}
+	return ""
+}
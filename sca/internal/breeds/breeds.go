@@ -0,0 +1,203 @@
+// Package breeds keeps the local breed catalog: it syncs it from TheCatAPI,
+// or loads the list bundled with the binary where TheCatAPI is out of
+// reach.
+package breeds
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"sca/sca/internal/clients/thecatapi"
+	"sca/sca/internal/logging"
+	"sca/sca/internal/models"
+	"sca/sca/internal/repository"
+)
+
+// seed is TheCatAPI's breed list as of the release, in its /breeds format
+// but with only the ID, name and origin of each breed.
+//
+//go:embed seed.json
+var seed []byte
+
+// Status describes the catalog and this process's latest sync.
+type Status struct {
+	Breeds int64 `json:"breeds"`
+	// SyncedAt and Source describe the latest sync or load that wrote the
+	// catalog, by any process.
+	SyncedAt *time.Time `json:"synced_at,omitempty"`
+	Source   string     `json:"source,omitempty"`
+	// LastError is the error of this process's latest sync if it failed.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Syncer writes breed lists into the catalog.
+type Syncer struct {
+	repo   repository.BreedRepository
+	client thecatapi.Client
+
+	mu      sync.Mutex
+	lastErr error
+}
+
+func NewSyncer(repo repository.BreedRepository, client thecatapi.Client) *Syncer {
+	return &Syncer{repo: repo, client: client}
+}
+
+// Sync stores TheCatAPI's breed list and returns how many breeds it had.
+// Breeds missing from the list are kept.
+func (s *Syncer) Sync(ctx context.Context) (int, error) {
+	list, err := s.client.ListBreeds(ctx)
+	if err == nil && len(list) == 0 {
+		err = errors.New("TheCatAPI returned no breeds")
+	}
+	if err != nil {
+		s.record(err)
+		return 0, err
+	}
+	return s.store(ctx, list, models.BreedSourceTheCatAPI)
+}
+
+// Load stores a breed list read from r in TheCatAPI's /breeds format,
+// recording source as its origin.
+func (s *Syncer) Load(ctx context.Context, r io.Reader, source string) (int, error) {
+	var list []thecatapi.Breed
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return 0, fmt.Errorf("decoding breeds: %w", err)
+	}
+	for i, b := range list {
+		if b.ID == "" || b.Name == "" {
+			return 0, fmt.Errorf("breed %d: id and name are required", i)
+		}
+	}
+	return s.store(ctx, list, source)
+}
+
+// Seed stores the breed list bundled with the binary.
+func (s *Syncer) Seed(ctx context.Context) (int, error) {
+	return s.Load(ctx, bytes.NewReader(seed), models.BreedSourceSeed)
+}
+
+// SeedIfEmpty runs Seed when the catalog has no breeds, so that a fresh or
+// air-gapped install can validate breeds before its first sync.
+func (s *Syncer) SeedIfEmpty(ctx context.Context) (int, error) {
+	st, err := s.repo.Stats(ctx)
+	if err != nil || st.Count > 0 {
+		return 0, err
+	}
+	return s.Seed(ctx)
+}
+
+// Run syncs right away and then every interval until ctx ends. A failed
+// sync is logged and leaves the catalog as it was.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	log := logging.From(ctx)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if n, err := s.Sync(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warn("breed sync failed", "err", err)
+		} else {
+			log.Info("breeds synced", "count", n, "source", models.BreedSourceTheCatAPI)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Status reports the catalog size and the latest sync.
+func (s *Syncer) Status(ctx context.Context) (Status, error) {
+	stats, err := s.repo.Stats(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	st := Status{Breeds: stats.Count, Source: stats.LastSource}
+	if !stats.LastSyncedAt.IsZero() {
+		st.SyncedAt = &stats.LastSyncedAt
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastErr != nil {
+		st.LastError = s.lastErr.Error()
+	}
+	return st, nil
+}
+
+func (s *Syncer) store(ctx context.Context, list []thecatapi.Breed, source string) (int, error) {
+	now := time.Now()
+	rows := make([]models.Breed, 0, len(list))
+	seen := map[string]bool{}
+	for _, b := range list {
+		// One upsert can't touch a row twice; the first entry wins.
+		if seen[b.ID] {
+			continue
+		}
+		seen[b.ID] = true
+		row := fromAPI(b)
+		row.Source, row.SyncedAt = source, now
+		rows = append(rows, row)
+	}
+	err := s.repo.Upsert(ctx, rows)
+	s.record(err)
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+func (s *Syncer) record(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+}
+
+func fromAPI(b thecatapi.Breed) models.Breed {
+	return models.Breed{
+		ID:           b.ID,
+		Name:         b.Name,
+		AltNames:     b.AltNames,
+		Origin:       b.Origin,
+		CountryCode:  b.CountryCode,
+		Description:  b.Description,
+		Temperament:  b.Temperament,
+		LifeSpan:     b.LifeSpan,
+		Weight:       models.BreedWeight{Imperial: b.Weight.Imperial, Metric: b.Weight.Metric},
+		WikipediaURL: b.WikipediaURL,
+		Traits: models.BreedTraits{
+			Adaptability:     b.Adaptability,
+			AffectionLevel:   b.AffectionLevel,
+			ChildFriendly:    b.ChildFriendly,
+			DogFriendly:      b.DogFriendly,
+			EnergyLevel:      b.EnergyLevel,
+			Grooming:         b.Grooming,
+			HealthIssues:     b.HealthIssues,
+			Intelligence:     b.Intelligence,
+			SheddingLevel:    b.SheddingLevel,
+			SocialNeeds:      b.SocialNeeds,
+			StrangerFriendly: b.StrangerFriendly,
+			Vocalisation:     b.Vocalisation,
+			Indoor:           b.Indoor == 1,
+			Lap:              b.Lap == 1,
+			Experimental:     b.Experimental == 1,
+			Hairless:         b.Hairless == 1,
+			Natural:          b.Natural == 1,
+			Rare:             b.Rare == 1,
+			Rex:              b.Rex == 1,
+			SuppressedTail:   b.SuppressedTail == 1,
+			ShortLegs:        b.ShortLegs == 1,
+			Hypoallergenic:   b.Hypoallergenic == 1,
+		},
+	}
+}
@@ -0,0 +1,130 @@
+package breeds
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"sca/sca/internal/clients/thecatapi"
+	"sca/sca/internal/models"
+	"sca/sca/internal/repository"
+)
+
+func TestSeedKeepsSyncedDetails(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemory().Breeds()
+	siamese := thecatapi.Breed{
+		ID: "siam", Name: "Siamese", Origin: "Thailand", CountryCode: "TH",
+		Temperament: "Active, Agile, Clever", LifeSpan: "12 - 15",
+		Weight:       thecatapi.Weight{Imperial: "8 - 15", Metric: "4 - 7"},
+		Intelligence: 5, Lap: 1,
+	}
+	if _, err := NewSyncer(repo, &thecatapi.Mock{Breeds: []thecatapi.Breed{siamese}}).Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	n, err := NewSyncer(repo, nil).Seed(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n < 60 {
+		t.Fatalf("seeded %d breeds", n)
+	}
+
+	b, err := repo.Find(ctx, "siam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Source != models.BreedSourceSeed {
+		t.Errorf("source %q, want seed", b.Source)
+	}
+	if b.Temperament != siamese.Temperament || b.LifeSpan != siamese.LifeSpan || b.Weight.Metric != "4 - 7" || b.CountryCode != "TH" {
+		t.Errorf("seed erased details: %+v", b)
+	}
+	if b.Traits.Intelligence != 5 || !b.Traits.Lap {
+		t.Errorf("seed erased traits: %+v", b.Traits)
+	}
+}
+
+func TestLoadRejectsIncompleteBreeds(t *testing.T) {
+	s := NewSyncer(repository.NewMemory().Breeds(), nil)
+	if _, err := s.Load(context.Background(), strings.NewReader(`[{"id":"siam"}]`), models.BreedSourceFile); err == nil {
+		t.Fatal("loaded a breed without a name")
+	}
+	if _, err := s.Load(context.Background(), strings.NewReader(`{`), models.BreedSourceFile); err == nil {
+		t.Fatal("loaded malformed JSON")
+	}
+}
+
+var catalog = []models.Breed{
+	{ID: "siam", Name: "Siamese", Origin: "Thailand", CountryCode: "TH", Temperament: "Active, Agile, Clever, Sociable, Loving, Energetic"},
+	{ID: "bsho", Name: "British Shorthair", AltNames: "Highlander, Highland Straight", Origin: "United Kingdom", CountryCode: "GB", Temperament: "Affectionate, Easy Going, Gentle, Loyal, Patient, calm"},
+	{ID: "asho", Name: "American Shorthair", Origin: "United States", CountryCode: "US", Temperament: "Active, Curious, Easy Going, Playful, Calm"},
+	{ID: "beng", Name: "Bengal", Origin: "United States", CountryCode: "US", Temperament: "Alert, Agile, Energetic, Demanding, Intelligent"},
+}
+
+func TestSearch(t *testing.T) {
+	for _, tc := range []struct {
+		q    Query
+		want []string
+	}{
+		{Query{Q: "Siamese"}, []string{"siam"}},
+		{Query{Q: "siames"}, []string{"siam"}},
+		{Query{Q: "siamees"}, []string{"siam"}},
+		{Query{Q: "bsho"}, []string{"bsho"}},
+		{Query{Q: "shorthair"}, []string{"asho", "bsho"}},
+		{Query{Q: "britsh short"}, []string{"bsho"}},
+		{Query{Q: "highlander"}, []string{"bsho"}},
+		{Query{Q: "sphynx"}, nil},
+		{Query{Q: strings.Repeat("siamese", 14)}, nil},
+		{Query{Origin: "us"}, []string{"asho", "beng"}},
+		{Query{Origin: "united"}, []string{"asho", "beng", "bsho"}},
+		{Query{Origin: "thailnd"}, []string{"siam"}},
+		{Query{Temperament: []string{"playfull"}}, []string{"asho"}},
+		{Query{Temperament: []string{"agile", "energetic"}}, []string{"beng", "siam"}},
+		{Query{Temperament: []string{"easygoing", "calm"}}, []string{"asho", "bsho"}},
+		{Query{Q: "shorthair", Origin: "united kingdom"}, []string{"bsho"}},
+		{Query{Q: "bengal", Temperament: []string{"calm"}}, nil},
+	} {
+		var got []string
+		for _, b := range Search(catalog, tc.q) {
+			got = append(got, b.ID)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("Search(%+v) = %v, want %v", tc.q, got, tc.want)
+		}
+	}
+}
+
+func TestSearchRanksCloserMatchesFirst(t *testing.T) {
+	list := []models.Breed{
+		{ID: "a", Name: "Siamese Mix"},
+		{ID: "b", Name: "Siamese"},
+		{ID: "c", Name: "Siamesse"},
+	}
+	var got []string
+	for _, b := range Search(list, Query{Q: "siamese"}) {
+		got = append(got, b.ID)
+	}
+	if want := []string{"b", "a", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"cat", "cat", 0},
+		{"cat", "cats", 1},
+		{"cat", "cut", 1},
+		{"siamese", "siaemse", 1},
+		{"kitten", "sitting", 3},
+	} {
+		if got := distance(tc.a, tc.b); got != tc.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
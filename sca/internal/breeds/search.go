@@ -0,0 +1,204 @@
+package breeds
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"sca/sca/internal/models"
+)
+
+// Query picks breeds out of the catalog. Empty fields match every breed.
+type Query struct {
+	// Q is matched against the breed's name and alternative names, or is
+	// its exact ID.
+	Q string
+	// Origin is matched against the breed's origin, or is its exact
+	// country code.
+	Origin string
+	// Temperament lists traits the breed must all have.
+	Temperament []string
+}
+
+// IsZero reports whether q matches every breed.
+func (q Query) IsZero() bool {
+	return strings.TrimSpace(q.Q) == "" && strings.TrimSpace(q.Origin) == "" && len(q.Temperament) == 0
+}
+
+// Search returns the breeds of list that match q, best matches first and
+// then by name.
+//
+// Matching ignores case and punctuation and tolerates typos. A text
+// matches a query if it equals it, contains it, or if every word of the
+// query starts a word of the text or is within one typo of one (two for
+// words of seven letters or more), a typo being a missing, extra, wrong or
+// swapped letter; the closer the match, the better.
+func Search(list []models.Breed, q Query) []models.Breed {
+	type hit struct {
+		breed models.Breed
+		score int
+	}
+	var hits []hit
+	for _, b := range list {
+		if score, ok := q.match(b); ok {
+			hits = append(hits, hit{b, score})
+		}
+	}
+	slices.SortStableFunc(hits, func(a, b hit) int {
+		return cmp.Or(cmp.Compare(a.score, b.score), cmp.Compare(a.breed.Name, b.breed.Name), cmp.Compare(a.breed.ID, b.breed.ID))
+	})
+	out := make([]models.Breed, len(hits))
+	for i, h := range hits {
+		out[i] = h.breed
+	}
+	return out
+}
+
+// match reports whether b matches q and how closely; lower is closer.
+func (q Query) match(b models.Breed) (int, bool) {
+	total := 0
+	if q.Q != "" {
+		score, ok := matchAny(q.Q, append([]string{b.Name}, splitList(b.AltNames)...))
+		if strings.EqualFold(strings.TrimSpace(q.Q), b.ID) {
+			score, ok = 0, true
+		}
+		if !ok {
+			return 0, false
+		}
+		total += score
+	}
+	if q.Origin != "" {
+		score, ok := fuzzyMatch(q.Origin, b.Origin)
+		if b.CountryCode != "" && strings.EqualFold(strings.TrimSpace(q.Origin), b.CountryCode) {
+			score, ok = 0, true
+		}
+		if !ok {
+			return 0, false
+		}
+		total += score
+	}
+	traits := splitList(b.Temperament)
+	for _, t := range q.Temperament {
+		score, ok := matchAny(t, traits)
+		if !ok {
+			return 0, false
+		}
+		total += score
+	}
+	return total, true
+}
+
+// matchAny is the closest match of query against any of texts.
+func matchAny(query string, texts []string) (int, bool) {
+	best, found := 0, false
+	for _, t := range texts {
+		if score, ok := fuzzyMatch(query, t); ok && (!found || score < best) {
+			best, found = score, true
+		}
+	}
+	return best, found
+}
+
+// fuzzyMatch scores query against text: 0 if they are equal, 1 if text
+// contains query (also with the spaces taken out, so "easygoing" finds
+// "Easy Going"), and 2 plus the edits needed if every query word matches
+// a word of text as described on Search.
+func fuzzyMatch(query, text string) (int, bool) {
+	qw, tw := words(query), words(text)
+	if len(qw) == 0 || len(tw) == 0 {
+		return 0, len(qw) == 0
+	}
+	qs, ts := strings.Join(qw, " "), strings.Join(tw, " ")
+	switch {
+	case qs == ts:
+		return 0, true
+	case strings.Contains(ts, qs), strings.Contains(strings.Join(tw, ""), strings.Join(qw, "")):
+		return 1, true
+	}
+	score := 2
+	for _, q := range qw {
+		best := -1
+		ql := utf8.RuneCountInString(q)
+		for _, t := range tw {
+			d := 0
+			if !strings.HasPrefix(t, q) {
+				// distance is at least the difference in length, so
+				// words that differ more than that are not worth it.
+				if diff := ql - utf8.RuneCountInString(t); diff > typos(q) || -diff > typos(q) {
+					continue
+				}
+				d = distance(q, t)
+				if d > typos(q) {
+					continue
+				}
+			}
+			if best < 0 || d < best {
+				best = d
+			}
+		}
+		if best < 0 {
+			return 0, false
+		}
+		score += best
+	}
+	return score, true
+}
+
+// typos is how many edits a query word may be off by.
+func typos(word string) int {
+	switch n := len([]rune(word)); {
+	case n >= 7:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
+
+// words lowercases s and splits it at anything that isn't a letter or a
+// digit.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// splitList splits a comma-separated list such as a temperament.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// distance is the number of insertions, deletions, substitutions and
+// swaps of adjacent letters that turn a into b (optimal string alignment).
+func distance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	d := make([][]int, len(ar)+1)
+	for i := range d {
+		d[i] = make([]int, len(br)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ar[i-1] == br[j-2] && ar[i-2] == br[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ar)][len(br)]
+}
@@ -0,0 +1,104 @@
+package thecatapi
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling TheCatAPI while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("catapi: circuit open")
+
+// Circuit breaker states, as reported in CacheState.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	// outcomeIgnored says nothing about the upstream, e.g. the caller gave
+	// up or the request itself was bad.
+	outcomeIgnored
+)
+
+// breaker counts consecutive failed calls. At threshold it opens and
+// rejects calls for cooldown; then it lets a single trial call through
+// (half-open), which closes it on success and opens it again on failure.
+// A nil breaker allows everything.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time // zero while closed
+	trial    bool      // the half-open trial call is in flight
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a call may go ahead. Every allowed call must be
+// followed by done.
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return true
+	}
+	if b.trial || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// done records the outcome of an allowed call.
+func (b *breaker) done(o outcome) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasTrial := b.trial
+	b.trial = false
+	switch o {
+	case outcomeSuccess:
+		b.failures = 0
+		b.openedAt = time.Time{}
+	case outcomeFailure:
+		b.failures++
+		if b.failures >= b.threshold || wasTrial {
+			b.openedAt = time.Now()
+		}
+	}
+}
+
+func (b *breaker) state() string {
+	if b == nil {
+		return CircuitClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openedAt.IsZero():
+		return CircuitClosed
+	case time.Since(b.openedAt) < b.cooldown:
+		return CircuitOpen
+	default:
+		return CircuitHalfOpen
+	}
+}
@@ -1,11 +1,54 @@
 package thecatapi
 
+import "context"
+
+// Breed is an entry of TheCatAPI's /breeds list. Scores run from 1 to 5
+// and flags are 0 or 1; both are 0 where the upstream leaves them out.
 type Breed struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	AltNames     string `json:"alt_names"`
+	Origin       string `json:"origin"`
+	CountryCode  string `json:"country_code"`
+	Description  string `json:"description"`
+	Temperament  string `json:"temperament"`
+	LifeSpan     string `json:"life_span"`
+	Weight       Weight `json:"weight"`
+	WikipediaURL string `json:"wikipedia_url"`
+
+	Adaptability     int `json:"adaptability"`
+	AffectionLevel   int `json:"affection_level"`
+	ChildFriendly    int `json:"child_friendly"`
+	DogFriendly      int `json:"dog_friendly"`
+	EnergyLevel      int `json:"energy_level"`
+	Grooming         int `json:"grooming"`
+	HealthIssues     int `json:"health_issues"`
+	Intelligence     int `json:"intelligence"`
+	SheddingLevel    int `json:"shedding_level"`
+	SocialNeeds      int `json:"social_needs"`
+	StrangerFriendly int `json:"stranger_friendly"`
+	Vocalisation     int `json:"vocalisation"`
+
+	Indoor         int `json:"indoor"`
+	Lap            int `json:"lap"`
+	Experimental   int `json:"experimental"`
+	Hairless       int `json:"hairless"`
+	Natural        int `json:"natural"`
+	Rare           int `json:"rare"`
+	Rex            int `json:"rex"`
+	SuppressedTail int `json:"suppressed_tail"`
+	ShortLegs      int `json:"short_legs"`
+	Hypoallergenic int `json:"hypoallergenic"`
+}
+
+// Weight is a weight range in pounds (Imperial) and kilograms (Metric),
+// e.g. "7 - 10".
+type Weight struct {
+	Imperial string `json:"imperial"`
+	Metric   string `json:"metric"`
 }
 
 type Client interface {
-	ListBreeds() ([]Breed, error)
-	ValidateBreed(nameOrID string) (bool, error)
+	ListBreeds(ctx context.Context) ([]Breed, error)
+	ValidateBreed(ctx context.Context, nameOrID string) (bool, error)
 }
@@ -1,71 +1,378 @@
 package thecatapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
+
+	"sca/sca/internal/config"
+	"sca/sca/internal/logging"
+	"sca/sca/internal/metrics"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"golang.org/x/sync/singleflight"
 )
 
 type HTTPClient struct {
 	baseURL string
 	apiKey  string
 	http    *http.Client
+	retry   retryPolicy
+	breaker *breaker
+
+	ttl          time.Duration
+	refreshAhead time.Duration
+	maxStale     time.Duration
+	// refreshes collapses concurrent refreshes into one upstream call.
+	refreshes singleflight.Group
 
 	mu        sync.RWMutex
 	cacheTill time.Time
 	cache     []Breed
-	ttl       time.Duration
+	etag      string
+	fetchedAt time.Time
+	lastErr   error
+	// retryAt holds off refreshing after a failed refresh: until then
+	// lookups get the cached list, expired or not, without a new call.
+	retryAt time.Time
+}
+
+// CacheState describes the breed cache for health reporting.
+type CacheState struct {
+	// State is "empty" before the first successful fetch, then "fresh" or
+	// "stale" depending on the TTL.
+	State     string     `json:"state"`
+	Breeds    int        `json:"breeds"`
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// StaleUntil is when an expired list stops being served on errors.
+	StaleUntil *time.Time `json:"stale_until,omitempty"`
+	// LastError is the error of the latest refresh if it failed.
+	LastError string `json:"last_error,omitempty"`
+	// Circuit is the circuit breaker state: "closed", "open" or
+	// "half_open".
+	Circuit string `json:"circuit"`
 }
 
-func NewHTTP(ttl time.Duration) *HTTPClient {
+// retryPolicy says how often and how long to wait before retrying a
+// request that failed transiently.
+type retryPolicy struct {
+	max       int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// backoff returns the wait before retry n (from 0): the base delay doubled
+// n times, capped, and jittered down by up to half so that clients failing
+// together don't retry together.
+func (p retryPolicy) backoff(n int) time.Duration {
+	d := p.maxDelay
+	if n < 32 && p.baseDelay<<n > 0 && p.baseDelay<<n < d {
+		d = p.baseDelay << n
+	}
+	if half := d / 2; half > 0 {
+		return d - half + rand.N(half)
+	}
+	return d
+}
+
+// statusError is a non-2xx response.
+type statusError struct {
+	code int
+	// retryAfter is the parsed Retry-After header, 0 when absent.
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string { return fmt.Sprintf("catapi: status %d", e.code) }
+
+// transient reports whether err may go away on retry: a network error, a
+// timeout of the attempt, a connection cut mid-response, 5xx or 429.
+// Anything else, such as a body that isn't a breed list, is permanent.
+// Errors after ctx ended are not transient either, as the caller has
+// stopped waiting.
+func transient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	}
+	var ne net.Error
+	return errors.As(err, &ne) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP date.
+func parseRetryAfter(h string, now time.Time) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(h); err == nil {
+		return max(time.Duration(secs)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+func NewHTTP(cfg config.TheCatAPI) *HTTPClient {
 	return &HTTPClient{
-		baseURL: "https://api.thecatapi.com/v1",
-		apiKey:  os.Getenv("THECATAPI_KEY"),
-		http:    &http.Client{Timeout: 10 * time.Second},
-		ttl:     ttl,
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		apiKey:  cfg.APIKey,
+		http: &http.Client{
+			Timeout: cfg.Timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport,
+				otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+					return "thecatapi " + r.Method + " " + r.URL.Path
+				})),
+		},
+		retry: retryPolicy{
+			max:       cfg.MaxRetries,
+			baseDelay: cfg.RetryBaseDelay,
+			maxDelay:  cfg.RetryMaxDelay,
+		},
+		breaker:      newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		ttl:          cfg.CacheTTL,
+		refreshAhead: cfg.RefreshAhead,
+		maxStale:     cfg.CacheMaxStale,
 	}
 }
 
-func (c *HTTPClient) ListBreeds() ([]Breed, error) {
+// ListBreeds returns the cached breed list, refreshing it in the background
+// shortly before it expires and in the foreground once it has. An expired
+// list is still returned for up to maxStale while refreshes fail.
+func (c *HTTPClient) ListBreeds(ctx context.Context) ([]Breed, error) {
+	now := time.Now()
 	c.mu.RLock()
-	if time.Now().Before(c.cacheTill) && c.cache != nil {
-		b := c.cache
+	list, till, retryAt := c.cache, c.cacheTill, c.retryAt
+	c.mu.RUnlock()
+	staleOK := list != nil && now.Before(till.Add(c.maxStale))
+
+	switch {
+	case list != nil && now.Before(till):
+		metrics.CatAPICacheLookup(metrics.CacheHit)
+		if till.Sub(now) <= c.refreshAhead && !now.Before(retryAt) {
+			// DoChan runs the refresh in its own goroutine; nobody waits.
+			c.refreshes.DoChan("breeds", c.refreshFunc(ctx))
+		}
+		return list, nil
+	case staleOK && now.Before(retryAt):
+		metrics.CatAPICacheLookup(metrics.CacheStale)
+		return list, nil
+	}
+
+	fresh, err := c.refresh(ctx)
+	if err != nil && staleOK && ctx.Err() == nil {
+		metrics.CatAPICacheLookup(metrics.CacheStale)
+		logging.From(ctx).Warn("serving stale breeds", "upstream", "thecatapi", "err", err, "expired_at", till)
+		return list, nil
+	}
+	metrics.CatAPICacheLookup(metrics.CacheMiss)
+	return fresh, err
+}
+
+// refresh waits for the refresh in flight, or starts one. The refresh
+// outlives ctx: it serves every waiter and the cache, so one caller giving
+// up must not fail it for the others.
+func (c *HTTPClient) refresh(ctx context.Context) ([]Breed, error) {
+	select {
+	case r := <-c.refreshes.DoChan("breeds", c.refreshFunc(ctx)):
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return r.Val.([]Breed), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *HTTPClient) refreshFunc(ctx context.Context) func() (any, error) {
+	ctx = context.WithoutCancel(ctx)
+	return func() (any, error) {
+		c.mu.RLock()
+		etag := c.etag
 		c.mu.RUnlock()
-		return b, nil
+
+		res, err := c.fetchWithRetry(ctx, etag)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.lastErr = err
+		if err != nil {
+			c.retryAt = time.Now().Add(c.retry.maxDelay)
+			return nil, err
+		}
+		if !res.notModified {
+			c.cache, c.etag = res.list, res.etag
+		}
+		c.fetchedAt = time.Now()
+		c.cacheTill = c.fetchedAt.Add(c.ttl)
+		c.retryAt = time.Time{}
+		return c.cache, nil
 	}
-	c.mu.RUnlock()
+}
+
+// fetched is the result of a breed list request.
+type fetched struct {
+	list []Breed
+	etag string
+	// notModified means the list is unchanged since the ETag sent.
+	notModified bool
+}
+
+// fetchWithRetry gets the breed list through the circuit breaker, retrying
+// transient failures while the policy and ctx allow. A non-empty etag makes
+// the request conditional.
+func (c *HTTPClient) fetchWithRetry(ctx context.Context, etag string) (fetched, error) {
+	log := logging.From(ctx).With("upstream", "thecatapi")
+	if !c.breaker.allow() {
+		metrics.CatAPIRequests.WithLabelValues("circuit_open").Inc()
+		log.Debug("breeds request rejected", "err", ErrCircuitOpen)
+		return fetched{}, ErrCircuitOpen
+	}
+	for attempt := 0; ; attempt++ {
+		res, err := c.fetch(ctx, log, etag)
+		if err == nil {
+			c.breaker.done(outcomeSuccess)
+			return res, nil
+		}
+		if !transient(ctx, err) {
+			c.breaker.done(outcomeIgnored)
+			return fetched{}, err
+		}
+		delay, ok := c.retryDelay(ctx, attempt, err)
+		if !ok {
+			c.breaker.done(outcomeFailure)
+			return fetched{}, err
+		}
+		log.Info("retrying breeds request", "err", err, "attempt", attempt+1, "delay", delay)
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			c.breaker.done(outcomeIgnored)
+			return fetched{}, err
+		case <-t.C:
+		}
+	}
+}
+
+// retryDelay returns how long to wait before retrying after attempt failed
+// with err, and false when it should not be retried: retries are used up,
+// the upstream asked for a longer pause than the policy allows, or ctx
+// would end first.
+func (c *HTTPClient) retryDelay(ctx context.Context, attempt int, err error) (time.Duration, bool) {
+	if attempt >= c.retry.max {
+		return 0, false
+	}
+	delay := c.retry.backoff(attempt)
+	var se *statusError
+	if errors.As(err, &se) && se.retryAfter > 0 {
+		if se.retryAfter > c.retry.maxDelay {
+			return 0, false
+		}
+		delay = se.retryAfter
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return 0, false
+	}
+	return delay, true
+}
+
+// fetch makes one request for the breed list.
+func (c *HTTPClient) fetch(ctx context.Context, log *slog.Logger, etag string) (res fetched, err error) {
+	start := time.Now()
+	defer func() {
+		elapsed := time.Since(start)
+		metrics.CatAPIDuration.Observe(elapsed.Seconds())
+		if err != nil {
+			metrics.CatAPIRequests.WithLabelValues("error").Inc()
+			log.Warn("breeds request failed", "err", err, "elapsed", elapsed)
+			return
+		}
+		metrics.CatAPIRequests.WithLabelValues("ok").Inc()
+		if res.notModified {
+			log.Debug("breeds not modified", "elapsed", elapsed)
+			return
+		}
+		log.Debug("breeds fetched", "count", len(res.list), "elapsed", elapsed)
+	}()
 
-	req, _ := http.NewRequest(http.MethodGet, c.baseURL+"/breeds", nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/breeds", nil)
+	if err != nil {
+		return res, err
+	}
 	if c.apiKey != "" {
 		req.Header.Set("x-api-key", c.apiKey)
 	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return nil, err
+		return res, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && etag != "" {
+		res.notModified = true
+		return res, nil
+	}
 	if resp.StatusCode/100 != 2 {
-		return nil, errors.New("catapi: non-200")
+		return res, &statusError{code: resp.StatusCode, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
 	}
-
-	var list []Breed
-	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
-		return nil, err
+	// Read first, so that a connection cut mid-body stays a transient
+	// read error and only a complete but bad body fails to decode.
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return res, err
+	}
+	if err := json.Unmarshal(data, &res.list); err != nil {
+		return res, fmt.Errorf("catapi: decoding breeds: %w", err)
 	}
+	if res.list == nil {
+		res.list = []Breed{}
+	}
+	res.etag = resp.Header.Get("ETag")
+	return res, nil
+}
 
-	c.mu.Lock()
-	c.cache = list
-	c.cacheTill = time.Now().Add(c.ttl)
-	c.mu.Unlock()
-	return list, nil
+// CacheState reports what the breed cache currently holds.
+func (c *HTTPClient) CacheState() CacheState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	st := CacheState{State: "empty", Breeds: len(c.cache)}
+	if c.cache != nil {
+		st.State = "fresh"
+		if time.Now().After(c.cacheTill) {
+			st.State = "stale"
+		}
+		fetchedAt, expires, staleUntil := c.fetchedAt, c.cacheTill, c.cacheTill.Add(c.maxStale)
+		st.FetchedAt, st.ExpiresAt, st.StaleUntil = &fetchedAt, &expires, &staleUntil
+	}
+	if c.lastErr != nil {
+		st.LastError = c.lastErr.Error()
+	}
+	st.Circuit = c.breaker.state()
+	return st
 }
 
-func (c *HTTPClient) ValidateBreed(nameOrID string) (bool, error) {
-	list, err := c.ListBreeds()
+func (c *HTTPClient) ValidateBreed(ctx context.Context, nameOrID string) (bool, error) {
+	list, err := c.ListBreeds(ctx)
 	if err != nil {
 		return false, err
 	}
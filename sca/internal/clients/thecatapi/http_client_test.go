@@ -0,0 +1,312 @@
+package thecatapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sca/sca/internal/config"
+)
+
+// upstream is an httptest stand-in for TheCatAPI that answers each request
+// with the next queued response, repeating the last one.
+type upstream struct {
+	*httptest.Server
+	calls atomic.Int32
+
+	mu      sync.Mutex
+	replies []func(w http.ResponseWriter)
+	times   []time.Time
+}
+
+func newUpstream(t *testing.T, replies ...func(w http.ResponseWriter)) *upstream {
+	t.Helper()
+	u := &upstream{replies: replies}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(u.calls.Add(1)) - 1
+		u.mu.Lock()
+		u.times = append(u.times, time.Now())
+		reply := u.replies[min(n, len(u.replies)-1)]
+		u.mu.Unlock()
+		reply(w)
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+// then replaces the queued responses.
+func (u *upstream) then(replies ...func(w http.ResponseWriter)) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.calls.Store(0)
+	u.replies = replies
+}
+
+// gap is the time between request i-1 and request i.
+func (u *upstream) gap(i int) time.Duration {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.times[i].Sub(u.times[i-1])
+}
+
+func status(code int, headers ...string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(code)
+	}
+}
+
+func body(s string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(s))
+	}
+}
+
+var breeds = body(`[{"id":"siam","name":"Siamese","origin":"Thailand"}]`)
+
+func newTestClient(u *upstream, edit func(*config.TheCatAPI)) *HTTPClient {
+	cfg := config.TheCatAPI{
+		BaseURL:        u.URL,
+		Timeout:        2 * time.Second,
+		MaxRetries:     2,
+		RetryBaseDelay: time.Millisecond,
+		RetryMaxDelay:  20 * time.Millisecond,
+	}
+	if edit != nil {
+		edit(&cfg)
+	}
+	return NewHTTP(cfg)
+}
+
+func TestRetriesTransientStatuses(t *testing.T) {
+	for _, code := range []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+		u := newUpstream(t, status(code), status(code), breeds)
+		list, err := newTestClient(u, nil).ListBreeds(context.Background())
+		if err != nil {
+			t.Fatalf("%d: %v", code, err)
+		}
+		if len(list) != 1 || list[0].ID != "siam" {
+			t.Fatalf("%d: got %+v", code, list)
+		}
+		if n := u.calls.Load(); n != 3 {
+			t.Fatalf("%d: %d calls, want 3", code, n)
+		}
+	}
+}
+
+func TestRetriesCutResponse(t *testing.T) {
+	cut := func(w http.ResponseWriter) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte(`[{"id":"si`))
+	}
+	u := newUpstream(t, cut, breeds)
+	if _, err := newTestClient(u, nil).ListBreeds(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := u.calls.Load(); n != 2 {
+		t.Fatalf("%d calls, want 2", n)
+	}
+}
+
+func TestDoesNotRetryPermanentErrors(t *testing.T) {
+	for name, reply := range map[string]func(http.ResponseWriter){
+		"not found":      status(http.StatusNotFound),
+		"bad request":    status(http.StatusBadRequest),
+		"malformed body": body(`{"oops`),
+		"wrong shape":    body(`{"id":"siam"}`),
+	} {
+		u := newUpstream(t, reply)
+		c := newTestClient(u, func(cfg *config.TheCatAPI) { cfg.BreakerThreshold = 1 })
+		if _, err := c.ListBreeds(context.Background()); err == nil {
+			t.Fatalf("%s: no error", name)
+		}
+		if n := u.calls.Load(); n != 1 {
+			t.Fatalf("%s: %d calls, want 1", name, n)
+		}
+		// A permanent error says nothing about the upstream's health.
+		if st := c.CacheState().Circuit; st != CircuitClosed {
+			t.Fatalf("%s: circuit %s, want closed", name, st)
+		}
+	}
+}
+
+func TestGivesUpWhenRetriesRunOut(t *testing.T) {
+	u := newUpstream(t, status(http.StatusBadGateway))
+	_, err := newTestClient(u, nil).ListBreeds(context.Background())
+	var se *statusError
+	if !errors.As(err, &se) || se.code != http.StatusBadGateway {
+		t.Fatalf("got %v, want status 502", err)
+	}
+	if n := u.calls.Load(); n != 3 {
+		t.Fatalf("%d calls, want 3", n)
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	u := newUpstream(t, status(http.StatusTooManyRequests, "Retry-After", "1"), breeds)
+	c := newTestClient(u, func(cfg *config.TheCatAPI) { cfg.RetryMaxDelay = 2 * time.Second })
+	if _, err := c.ListBreeds(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if gap := u.gap(1); gap < time.Second {
+		t.Fatalf("retried after %v, want at least 1s", gap)
+	}
+}
+
+func TestRetryAfterDate(t *testing.T) {
+	at := time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat)
+	u := newUpstream(t, status(http.StatusServiceUnavailable, "Retry-After", at), breeds)
+	c := newTestClient(u, func(cfg *config.TheCatAPI) { cfg.RetryMaxDelay = 3 * time.Second })
+	if _, err := c.ListBreeds(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The date has a resolution of one second.
+	if gap := u.gap(1); gap < time.Second {
+		t.Fatalf("retried after %v, want at least 1s", gap)
+	}
+}
+
+func TestRetryAfterBeyondMaxDelayGivesUp(t *testing.T) {
+	u := newUpstream(t, status(http.StatusServiceUnavailable, "Retry-After", "3600"), breeds)
+	if _, err := newTestClient(u, nil).ListBreeds(context.Background()); err == nil {
+		t.Fatal("no error")
+	}
+	if n := u.calls.Load(); n != 1 {
+		t.Fatalf("%d calls, want 1", n)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for h, want := range map[string]time.Duration{
+		"":                              0,
+		"7":                             7 * time.Second,
+		"-3":                            0,
+		"soon":                          0,
+		"Fri, 02 Jan 2026 03:04:35 GMT": 30 * time.Second,
+		"Fri, 02 Jan 2026 03:00:00 GMT": 0,
+	} {
+		if got := parseRetryAfter(h, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", h, got, want)
+		}
+	}
+}
+
+func TestBreakerOpensHalfOpensAndCloses(t *testing.T) {
+	u := newUpstream(t, status(http.StatusInternalServerError))
+	c := newTestClient(u, func(cfg *config.TheCatAPI) {
+		cfg.MaxRetries = 0
+		cfg.BreakerThreshold = 2
+		cfg.BreakerCooldown = 100 * time.Millisecond
+	})
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := c.fetchWithRetry(ctx, ""); err == nil {
+			t.Fatal("no error")
+		}
+	}
+	if st := c.CacheState().Circuit; st != CircuitOpen {
+		t.Fatalf("circuit %s, want open", st)
+	}
+
+	// Open: fail fast without calling the upstream.
+	if _, err := c.fetchWithRetry(ctx, ""); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v, want ErrCircuitOpen", err)
+	}
+	if n := u.calls.Load(); n != 2 {
+		t.Fatalf("%d calls, want 2", n)
+	}
+
+	// Half-open: a failed trial opens it again.
+	time.Sleep(120 * time.Millisecond)
+	if st := c.CacheState().Circuit; st != CircuitHalfOpen {
+		t.Fatalf("circuit %s, want half_open", st)
+	}
+	if _, err := c.fetchWithRetry(ctx, ""); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("trial got %v, want the upstream's error", err)
+	}
+	if st := c.CacheState().Circuit; st != CircuitOpen {
+		t.Fatalf("circuit %s after failed trial, want open", st)
+	}
+
+	// Half-open again: a successful trial closes it.
+	time.Sleep(120 * time.Millisecond)
+	u.then(breeds)
+	if _, err := c.fetchWithRetry(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if st := c.CacheState().Circuit; st != CircuitClosed {
+		t.Fatalf("circuit %s, want closed", st)
+	}
+}
+
+func TestBreakerAllowsOneTrialAtATime(t *testing.T) {
+	b := newBreaker(1, time.Millisecond)
+	if !b.allow() {
+		t.Fatal("closed breaker refused")
+	}
+	b.done(outcomeFailure)
+	time.Sleep(2 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("half-open breaker refused the trial")
+	}
+	if b.allow() {
+		t.Fatal("half-open breaker allowed a second call during the trial")
+	}
+	b.done(outcomeSuccess)
+	if !b.allow() || b.state() != CircuitClosed {
+		t.Fatal("breaker not closed after a successful trial")
+	}
+}
+
+func TestCancelStopsBackoff(t *testing.T) {
+	u := newUpstream(t, status(http.StatusServiceUnavailable))
+	c := newTestClient(u, func(cfg *config.TheCatAPI) {
+		cfg.RetryBaseDelay = 10 * time.Second
+		cfg.RetryMaxDelay = 10 * time.Second
+		cfg.BreakerThreshold = 1
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	if _, err := c.fetchWithRetry(ctx, ""); err == nil {
+		t.Fatal("no error")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("returned after %v, want right after the cancel", elapsed)
+	}
+	if n := u.calls.Load(); n != 1 {
+		t.Fatalf("%d calls, want 1", n)
+	}
+	// Giving up is not the upstream's fault.
+	if st := c.CacheState().Circuit; st != CircuitClosed {
+		t.Fatalf("circuit %s, want closed", st)
+	}
+}
+
+func TestDeadlineTooCloseForBackoffGivesUp(t *testing.T) {
+	u := newUpstream(t, status(http.StatusServiceUnavailable))
+	c := newTestClient(u, func(cfg *config.TheCatAPI) {
+		cfg.RetryBaseDelay = 10 * time.Second
+		cfg.RetryMaxDelay = 10 * time.Second
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var se *statusError
+	if _, err := c.fetchWithRetry(ctx, ""); !errors.As(err, &se) {
+		t.Fatalf("got %v, want the upstream's status", err)
+	}
+	if n := u.calls.Load(); n != 1 {
+		t.Fatalf("%d calls, want 1", n)
+	}
+}
@@ -1,12 +1,14 @@
 package thecatapi
 
+import "context"
+
 type Mock struct {
 	Breeds []Breed
 	Err    error
 }
 
-func (m *Mock) ListBreeds() ([]Breed, error) { return m.Breeds, m.Err }
-func (m *Mock) ValidateBreed(s string) (bool, error) {
+func (m *Mock) ListBreeds(context.Context) ([]Breed, error) { return m.Breeds, m.Err }
+func (m *Mock) ValidateBreed(_ context.Context, s string) (bool, error) {
 	if m.Err != nil {
 		return false, m.Err
 	}
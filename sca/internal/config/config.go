@@ -0,0 +1,419 @@
+// Package config holds the typed application configuration. It is loaded
+// from, in increasing precedence, built-in defaults, a YAML or TOML file,
+// environment variables and command-line flags (see Load), and validated
+// before anything starts.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// Env is "dev" or "prod"; dev changes the logging defaults.
+	Env        string
+	HTTP       HTTP
+	Database   Database
+	Log        Log
+	TheCatAPI  TheCatAPI
+	Breeds     Breeds
+	Missions   Missions
+	Metrics    Metrics
+	Tracing    Tracing
+	Auth       Auth
+	Encryption Encryption
+}
+
+type HTTP struct {
+	Addr              string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownDelay is how long /healthz and /readyz report not-ready before the
+	// listener closes, so load balancers stop routing to this instance.
+	ShutdownDelay time.Duration
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// once shutdown starts; connections still open after it are dropped.
+	ShutdownTimeout time.Duration
+	// ReadinessTimeout bounds the dependency checks behind /readyz.
+	ReadinessTimeout time.Duration
+	// TrustedProxies lists the proxy IPs and CIDRs whose X-Forwarded-For and
+	// X-Real-IP headers name the client. Empty trusts no proxy: the client
+	// is the peer address.
+	TrustedProxies []string
+}
+
+type Database struct {
+	// URL, when set, is used as is and the discrete fields are ignored.
+	URL             string
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	Name            string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// SlowQuery is the duration above which queries are logged at warn.
+	SlowQuery time.Duration
+	// MigrationsDir replaces the migrations embedded in the binary.
+	MigrationsDir string
+}
+
+// DSN returns URL, or a key/value connection string built from the
+// discrete fields.
+func (d Database) DSN() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Password, d.Name, d.SSLMode)
+}
+
+type Log struct {
+	// Format is "json" or "text"; empty means text in dev, json otherwise.
+	Format string
+	// Level is debug, info, warn or error; empty means debug in dev, info
+	// otherwise.
+	Level string
+	// Bodies enables request/response body logging; unset means on in dev.
+	Bodies       *bool
+	BodyMaxBytes int
+	BodyRedact   []string
+}
+
+// SlogLevel parses Level; call it on a validated Config.
+func (l Log) SlogLevel() slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(l.Level))
+	return level
+}
+
+type TheCatAPI struct {
+	BaseURL string
+	APIKey  string
+	// Timeout bounds one request; retries get a fresh one each.
+	Timeout  time.Duration
+	CacheTTL time.Duration
+	// RefreshAhead is how long before the cache expires a lookup starts a
+	// background refresh. 0 refreshes only once it has expired.
+	RefreshAhead time.Duration
+	// CacheMaxStale is how long past its expiry the cached list is still
+	// served when refreshing fails. 0 never serves it expired.
+	CacheMaxStale time.Duration
+	// MaxRetries is how many times a request failing with a network error,
+	// 5xx or 429 is retried.
+	MaxRetries int
+	// RetryBaseDelay and RetryMaxDelay bound the jittered exponential
+	// backoff between attempts. A Retry-After longer than RetryMaxDelay
+	// ends the retries.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// BreakerThreshold consecutive failed calls open the circuit breaker,
+	// which then fails calls without a request for BreakerCooldown. 0
+	// disables the breaker.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+type Breeds struct {
+	// SyncInterval is how often the server syncs the breed catalog from
+	// TheCatAPI, starting at startup. 0 leaves syncing to `sca breeds
+	// sync`.
+	SyncInterval time.Duration
+}
+
+type Missions struct {
+	// AutoComplete completes an active mission when its last open target
+	// is completed or deleted.
+	AutoComplete bool
+	// RequireTargetsDone rejects manual completion while targets are open
+	// unless forced.
+	RequireTargetsDone bool
+}
+
+type Metrics struct {
+	// Enabled serves Prometheus metrics on /metrics.
+	Enabled bool
+}
+
+type Tracing struct {
+	// Exporter is "none", "stdout" or "otlp".
+	Exporter string
+	// OTLPEndpoint is the host:port of an OTLP/HTTP collector.
+	OTLPEndpoint string
+	OTLPInsecure bool
+	// SampleRatio is the share of new traces recorded; traces started by a
+	// caller follow the caller's decision.
+	SampleRatio float64
+	ServiceName string
+}
+
+type Auth struct {
+	// Enabled requires an API key or a JWT on every /api/v1 route.
+	Enabled bool
+	// JWTSecret verifies HS256 tokens; empty rejects them.
+	JWTSecret string
+	// JWKSFile is a local JSON Web Key Set with the RSA keys that verify
+	// RS256 tokens; empty rejects them.
+	JWKSFile string
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// Leeway absorbs clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+}
+
+type Encryption struct {
+	// Keys are the master keys that wrap the data keys of target notes:
+	// base64-encoded 32-byte keys separated by commas or newlines, current
+	// key first. Empty stores notes in plaintext.
+	Keys string
+}
+
+// Default returns the built-in configuration.
+func Default() *Config {
+	return &Config{
+		Env: "prod",
+		HTTP: HTTP{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+			ReadinessTimeout:  2 * time.Second,
+		},
+		Database: Database{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "sca",
+			Password:        "sca",
+			Name:            "sca",
+			SSLMode:         "disable",
+			MaxOpenConns:    20,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			SlowQuery:       200 * time.Millisecond,
+		},
+		Log: Log{
+			BodyMaxBytes: 4096,
+			// Hide target notes (also as notes diffs), salaries and
+			// credentials.
+			BodyRedact: []string{
+				"**.notes", "**.lines.*.text", "**.unified",
+				"**.salary_cents", "**.api_key", "**.x-api-key",
+				"**.password", "**.secret", "**.token",
+			},
+		},
+		TheCatAPI: TheCatAPI{
+			BaseURL:          "https://api.thecatapi.com/v1",
+			Timeout:          10 * time.Second,
+			CacheTTL:         10 * time.Minute,
+			RefreshAhead:     time.Minute,
+			CacheMaxStale:    24 * time.Hour,
+			MaxRetries:       2,
+			RetryBaseDelay:   200 * time.Millisecond,
+			RetryMaxDelay:    2 * time.Second,
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+		},
+		Breeds:   Breeds{SyncInterval: 24 * time.Hour},
+		Missions: Missions{AutoComplete: true, RequireTargetsDone: true},
+		Metrics:  Metrics{Enabled: true},
+		Tracing: Tracing{
+			Exporter:     "none",
+			OTLPEndpoint: "localhost:4318",
+			SampleRatio:  1,
+			ServiceName:  "sca",
+		},
+		Auth: Auth{Enabled: true, Leeway: 30 * time.Second},
+	}
+}
+
+func (c *Config) Dev() bool { return c.Env == "dev" }
+
+// resolve fills the settings whose defaults depend on Env.
+func (c *Config) resolve() {
+	if c.Log.Format == "" {
+		c.Log.Format = "json"
+		if c.Dev() {
+			c.Log.Format = "text"
+		}
+	}
+	if c.Log.Level == "" {
+		c.Log.Level = "info"
+		if c.Dev() {
+			c.Log.Level = "debug"
+		}
+	}
+	if c.Log.Bodies == nil {
+		on := c.Dev()
+		c.Log.Bodies = &on
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	bad := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Env != "dev" && c.Env != "prod" {
+		bad("env", "must be dev or prod, got %q", c.Env)
+	}
+
+	if c.HTTP.Addr == "" {
+		bad("http.addr", "is required")
+	}
+	for _, d := range []struct {
+		key string
+		v   time.Duration
+	}{
+		{"http.read_header_timeout", c.HTTP.ReadHeaderTimeout},
+		{"http.read_timeout", c.HTTP.ReadTimeout},
+		{"http.write_timeout", c.HTTP.WriteTimeout},
+		{"http.idle_timeout", c.HTTP.IdleTimeout},
+		{"http.shutdown_delay", c.HTTP.ShutdownDelay},
+		{"http.shutdown_timeout", c.HTTP.ShutdownTimeout},
+		{"http.readiness_timeout", c.HTTP.ReadinessTimeout},
+		{"database.conn_max_lifetime", c.Database.ConnMaxLifetime},
+		{"database.slow_query", c.Database.SlowQuery},
+	} {
+		if d.v < 0 {
+			bad(d.key, "must not be negative")
+		}
+	}
+
+	for _, p := range c.HTTP.TrustedProxies {
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				bad("http.trusted_proxies", "%q is not an IP address or CIDR", p)
+			}
+		}
+	}
+
+	if c.Database.URL != "" {
+		if u, err := url.Parse(c.Database.URL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			bad("database.url", "must be a postgres:// URL")
+		}
+	} else {
+		if c.Database.Host == "" {
+			bad("database.host", "is required when database.url is not set")
+		}
+		if c.Database.Port < 1 || c.Database.Port > 65535 {
+			bad("database.port", "must be between 1 and 65535")
+		}
+		if c.Database.Name == "" {
+			bad("database.name", "is required when database.url is not set")
+		}
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		bad("database.max_open_conns", "pool sizes must not be negative")
+	}
+
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		bad("log.format", "must be json or text, got %q", c.Log.Format)
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		bad("log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	if c.Log.BodyMaxBytes < 0 {
+		bad("log.body_max_bytes", "must not be negative")
+	}
+
+	if u, err := url.Parse(c.TheCatAPI.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		bad("thecatapi.base_url", "must be an http(s) URL")
+	}
+	if c.TheCatAPI.Timeout <= 0 {
+		bad("thecatapi.timeout", "must be positive")
+	}
+	if c.TheCatAPI.CacheTTL <= 0 {
+		bad("thecatapi.cache_ttl", "must be positive")
+	}
+	if c.TheCatAPI.RefreshAhead < 0 || c.TheCatAPI.RefreshAhead >= c.TheCatAPI.CacheTTL {
+		bad("thecatapi.refresh_ahead", "must be at least 0 and less than thecatapi.cache_ttl")
+	}
+	if c.TheCatAPI.CacheMaxStale < 0 {
+		bad("thecatapi.cache_max_stale", "must not be negative")
+	}
+	if c.TheCatAPI.MaxRetries < 0 {
+		bad("thecatapi.max_retries", "must not be negative")
+	}
+	if c.TheCatAPI.RetryBaseDelay <= 0 {
+		bad("thecatapi.retry_base_delay", "must be positive")
+	}
+	if c.TheCatAPI.RetryMaxDelay < c.TheCatAPI.RetryBaseDelay {
+		bad("thecatapi.retry_max_delay", "must be at least thecatapi.retry_base_delay")
+	}
+	if c.TheCatAPI.BreakerThreshold < 0 {
+		bad("thecatapi.breaker_threshold", "must not be negative")
+	}
+	if c.TheCatAPI.BreakerCooldown <= 0 {
+		bad("thecatapi.breaker_cooldown", "must be positive")
+	}
+
+	if c.Breeds.SyncInterval < 0 {
+		bad("breeds.sync_interval", "must not be negative")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.OTLPEndpoint == "" {
+			bad("tracing.otlp_endpoint", "is required with the otlp exporter")
+		}
+	default:
+		bad("tracing.exporter", "must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		bad("tracing.sample_ratio", "must be between 0 and 1")
+	}
+	if c.Tracing.ServiceName == "" {
+		bad("tracing.service_name", "is required")
+	}
+
+	if c.Auth.JWTSecret != "" && len(c.Auth.JWTSecret) < 32 {
+		bad("auth.jwt_secret", "must be at least 32 bytes")
+	}
+	if c.Auth.Leeway < 0 {
+		bad("auth.leeway", "must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Masked returns a copy of c with secrets replaced, for display.
+func (c *Config) Masked() *Config {
+	m := *c
+	m.Database.Password = mask(m.Database.Password)
+	m.TheCatAPI.APIKey = mask(m.TheCatAPI.APIKey)
+	m.Auth.JWTSecret = mask(m.Auth.JWTSecret)
+	m.Encryption.Keys = mask(m.Encryption.Keys)
+	if u, err := url.Parse(m.Database.URL); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), maskValue)
+			// String escapes the mask; undo it to keep the output readable.
+			m.Database.URL = strings.Replace(u.String(), url.PathEscape(maskValue), maskValue, 1)
+		}
+	}
+	return &m
+}
+
+const maskValue = "****"
+
+func mask(s string) string {
+	if s == "" {
+		return ""
+	}
+	return maskValue
+}
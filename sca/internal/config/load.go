@@ -0,0 +1,359 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable holding the config file path when
+// --config is not given.
+const FileEnv = "SCA_CONFIG"
+
+// field ties one setting to its file key (also the flag name) and its
+// environment variable.
+type field struct {
+	key   string
+	env   string
+	usage string
+	v     value
+}
+
+// value is a typed setting that can be parsed from text and shown.
+type value interface {
+	Set(s string) error
+	Get() any
+}
+
+func (c *Config) fields() []field {
+	return []field{
+		{"env", "APP_ENV", "dev or prod; dev switches logging to text, debug and bodies", (*stringValue)(&c.Env)},
+
+		{"http.addr", "HTTP_ADDR", "listen address", (*stringValue)(&c.HTTP.Addr)},
+		{"http.read_header_timeout", "HTTP_READ_HEADER_TIMEOUT", "time allowed to read request headers", (*durationValue)(&c.HTTP.ReadHeaderTimeout)},
+		{"http.read_timeout", "HTTP_READ_TIMEOUT", "time allowed to read a whole request", (*durationValue)(&c.HTTP.ReadTimeout)},
+		{"http.write_timeout", "HTTP_WRITE_TIMEOUT", "time allowed to write a response", (*durationValue)(&c.HTTP.WriteTimeout)},
+		{"http.idle_timeout", "HTTP_IDLE_TIMEOUT", "keep-alive idle timeout", (*durationValue)(&c.HTTP.IdleTimeout)},
+		{"http.shutdown_delay", "HTTP_SHUTDOWN_DELAY", "how long /readyz reports not-ready before the listener closes", (*durationValue)(&c.HTTP.ShutdownDelay)},
+		{"http.shutdown_timeout", "HTTP_SHUTDOWN_TIMEOUT", "how long in-flight requests may take to finish on shutdown", (*durationValue)(&c.HTTP.ShutdownTimeout)},
+		{"http.readiness_timeout", "HTTP_READINESS_TIMEOUT", "time allowed for the /readyz dependency checks", (*durationValue)(&c.HTTP.ReadinessTimeout)},
+		{"http.trusted_proxies", "HTTP_TRUSTED_PROXIES", "comma-separated proxy IPs or CIDRs allowed to set X-Forwarded-For", (*listValue)(&c.HTTP.TrustedProxies)},
+
+		{"database.url", "DATABASE_URL", "postgres:// connection URL; overrides the other database.* connection settings", (*stringValue)(&c.Database.URL)},
+		{"database.host", "POSTGRES_HOST", "database host", (*stringValue)(&c.Database.Host)},
+		{"database.port", "POSTGRES_PORT", "database port", (*intValue)(&c.Database.Port)},
+		{"database.user", "POSTGRES_USER", "database user", (*stringValue)(&c.Database.User)},
+		{"database.password", "POSTGRES_PASSWORD", "database password", (*stringValue)(&c.Database.Password)},
+		{"database.name", "POSTGRES_DB", "database name", (*stringValue)(&c.Database.Name)},
+		{"database.sslmode", "POSTGRES_SSLMODE", "libpq sslmode", (*stringValue)(&c.Database.SSLMode)},
+		{"database.max_open_conns", "DB_MAX_OPEN_CONNS", "connection pool size (0 = unlimited)", (*intValue)(&c.Database.MaxOpenConns)},
+		{"database.max_idle_conns", "DB_MAX_IDLE_CONNS", "idle connections kept in the pool", (*intValue)(&c.Database.MaxIdleConns)},
+		{"database.conn_max_lifetime", "DB_CONN_MAX_LIFETIME", "recycle connections after this long (0 = never)", (*durationValue)(&c.Database.ConnMaxLifetime)},
+		{"database.slow_query", "DB_SLOW_QUERY", "log queries slower than this at warn", (*durationValue)(&c.Database.SlowQuery)},
+		{"database.migrations_dir", "MIGRATIONS_DIR", "read migrations from this directory instead of the embedded copy", (*stringValue)(&c.Database.MigrationsDir)},
+
+		{"log.format", "LOG_FORMAT", "json or text", (*stringValue)(&c.Log.Format)},
+		{"log.level", "LOG_LEVEL", "debug, info, warn or error", (*stringValue)(&c.Log.Level)},
+		{"log.bodies", "LOG_BODIES", "log request and response bodies at debug level", &boolPtrValue{&c.Log.Bodies}},
+		{"log.body_max_bytes", "LOG_BODY_MAX_BYTES", "cut logged bodies after this many bytes", (*intValue)(&c.Log.BodyMaxBytes)},
+		{"log.body_redact", "LOG_BODY_REDACT", "comma-separated JSON paths redacted in logged bodies", (*listValue)(&c.Log.BodyRedact)},
+
+		{"thecatapi.base_url", "THECATAPI_BASE_URL", "TheCatAPI base URL", (*stringValue)(&c.TheCatAPI.BaseURL)},
+		{"thecatapi.api_key", "THECATAPI_KEY", "TheCatAPI key", (*stringValue)(&c.TheCatAPI.APIKey)},
+		{"thecatapi.timeout", "THECATAPI_TIMEOUT", "timeout of one TheCatAPI request", (*durationValue)(&c.TheCatAPI.Timeout)},
+		{"thecatapi.cache_ttl", "THECATAPI_CACHE_TTL", "how long the breed list is cached", (*durationValue)(&c.TheCatAPI.CacheTTL)},
+		{"thecatapi.refresh_ahead", "THECATAPI_REFRESH_AHEAD", "refresh the breed list in the background this long before it expires (0 = off)", (*durationValue)(&c.TheCatAPI.RefreshAhead)},
+		{"thecatapi.cache_max_stale", "THECATAPI_CACHE_MAX_STALE", "how long past expiry the breed list is served while refreshing fails (0 = never)", (*durationValue)(&c.TheCatAPI.CacheMaxStale)},
+		{"thecatapi.max_retries", "THECATAPI_MAX_RETRIES", "retries of a TheCatAPI request failing with a network error, 5xx or 429", (*intValue)(&c.TheCatAPI.MaxRetries)},
+		{"thecatapi.retry_base_delay", "THECATAPI_RETRY_BASE_DELAY", "backoff before the first retry, doubled for each further one", (*durationValue)(&c.TheCatAPI.RetryBaseDelay)},
+		{"thecatapi.retry_max_delay", "THECATAPI_RETRY_MAX_DELAY", "longest wait between retries, including Retry-After", (*durationValue)(&c.TheCatAPI.RetryMaxDelay)},
+		{"thecatapi.breaker_threshold", "THECATAPI_BREAKER_THRESHOLD", "consecutive failed calls that open the circuit breaker (0 = off)", (*intValue)(&c.TheCatAPI.BreakerThreshold)},
+		{"thecatapi.breaker_cooldown", "THECATAPI_BREAKER_COOLDOWN", "how long the open circuit breaker fails calls before trying again", (*durationValue)(&c.TheCatAPI.BreakerCooldown)},
+
+		{"breeds.sync_interval", "BREEDS_SYNC_INTERVAL", "how often to sync the breed catalog from TheCatAPI (0 = only on demand with sca breeds sync)", (*durationValue)(&c.Breeds.SyncInterval)},
+
+		{"missions.auto_complete", "MISSION_AUTO_COMPLETE", "complete an active mission when its last open target is completed or deleted", (*boolValue)(&c.Missions.AutoComplete)},
+		{"missions.require_targets_done", "MISSION_REQUIRE_TARGETS_DONE", "reject manual completion while targets are open unless forced", (*boolValue)(&c.Missions.RequireTargetsDone)},
+
+		{"metrics.enabled", "METRICS_ENABLED", "serve Prometheus metrics on /metrics", (*boolValue)(&c.Metrics.Enabled)},
+
+		{"tracing.exporter", "TRACING_EXPORTER", "none, stdout or otlp", (*stringValue)(&c.Tracing.Exporter)},
+		{"tracing.otlp_endpoint", "TRACING_OTLP_ENDPOINT", "host:port of the OTLP/HTTP collector", (*stringValue)(&c.Tracing.OTLPEndpoint)},
+		{"tracing.otlp_insecure", "TRACING_OTLP_INSECURE", "talk plain HTTP to the collector", (*boolValue)(&c.Tracing.OTLPInsecure)},
+		{"tracing.sample_ratio", "TRACING_SAMPLE_RATIO", "share of new traces to record (0-1)", (*floatValue)(&c.Tracing.SampleRatio)},
+		{"tracing.service_name", "TRACING_SERVICE_NAME", "service.name reported with spans", (*stringValue)(&c.Tracing.ServiceName)},
+
+		{"auth.enabled", "AUTH_ENABLED", "require an API key or a JWT on /api/v1", (*boolValue)(&c.Auth.Enabled)},
+		{"auth.jwt_secret", "AUTH_JWT_SECRET", "HS256 secret for bearer tokens (at least 32 bytes)", (*stringValue)(&c.Auth.JWTSecret)},
+		{"auth.jwks_file", "AUTH_JWKS_FILE", "JWKS file with the RSA keys for RS256 bearer tokens", (*stringValue)(&c.Auth.JWKSFile)},
+		{"auth.issuer", "AUTH_JWT_ISSUER", "required iss claim", (*stringValue)(&c.Auth.Issuer)},
+		{"auth.audience", "AUTH_JWT_AUDIENCE", "required aud claim", (*stringValue)(&c.Auth.Audience)},
+		{"auth.leeway", "AUTH_JWT_LEEWAY", "clock skew allowed when checking token times", (*durationValue)(&c.Auth.Leeway)},
+
+		{"encryption.keys", "ENCRYPTION_KEYS", "base64 master keys for target notes, current first (usually via ENCRYPTION_KEYS_FILE)", (*stringValue)(&c.Encryption.Keys)},
+	}
+}
+
+// Load registers --config and one flag per setting on fs, parses args and
+// builds the configuration from defaults, the config file (--config, or
+// $SCA_CONFIG), environment variables and the flags, each layer overriding
+// the previous one. For every variable NAME, NAME_FILE may instead name a
+// file holding the value, which is how secrets are usually mounted.
+// Positional arguments are left in fs.Args().
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	fields := cfg.fields()
+
+	path := fs.String("config", "", "config file (YAML, or TOML with a .toml extension); env "+FileEnv)
+	var set []flagValue
+	for _, f := range fields {
+		fv := flagValue{field: f, set: &set}
+		if _, ok := f.v.(*boolValue); ok {
+			fv.isBool = true
+		} else if _, ok := f.v.(*boolPtrValue); ok {
+			fv.isBool = true
+		}
+		fs.Var(fv, f.key, fmt.Sprintf("%s (env %s)", f.usage, f.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path == "" {
+		*path = os.Getenv(FileEnv)
+	}
+	if *path != "" {
+		if err := loadFile(*path, fields); err != nil {
+			return nil, err
+		}
+	}
+	if err := loadEnv(fields); err != nil {
+		return nil, err
+	}
+	for _, fv := range set {
+		if err := fv.field.v.Set(fv.raw); err != nil {
+			return nil, fmt.Errorf("-%s: %w", fv.field.key, err)
+		}
+	}
+
+	cfg.resolve()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// flagValue records a flag so it can be applied after the file and the
+// environment.
+type flagValue struct {
+	field  field
+	set    *[]flagValue
+	raw    string
+	isBool bool
+}
+
+func (f flagValue) String() string   { return "" }
+func (f flagValue) IsBoolFlag() bool { return f.isBool }
+
+func (f flagValue) Set(s string) error {
+	f.raw = s
+	*f.set = append(*f.set, f)
+	return nil
+}
+
+func loadFile(path string, fields []field) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	var doc map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", doc, values)
+	byKey := map[string]field{}
+	for _, f := range fields {
+		byKey[f.key] = f
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, k := range keys {
+		f, ok := byKey[k]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown setting", k))
+			continue
+		}
+		if err := f.v.Set(values[k]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", k, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("config file %s:\n%w", path, err)
+	}
+	return nil
+}
+
+// flatten turns nested tables into dotted keys with textual values, the
+// same form environment variables and flags have.
+func flatten(prefix string, doc map[string]any, out map[string]string) {
+	for k, v := range doc {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(key, v, out)
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			out[key] = strings.Join(items, ",")
+		case nil:
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+func loadEnv(fields []field) error {
+	var errs []error
+	for _, f := range fields {
+		s, ok, err := lookupEnv(f.env)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := f.v.Set(s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lookupEnv returns $name, or the contents of the file named by
+// $name_FILE without the trailing newline. Empty variables count as unset.
+func lookupEnv(name string) (string, bool, error) {
+	s := os.Getenv(name)
+	file := os.Getenv(name + "_FILE")
+	switch {
+	case s != "" && file != "":
+		return "", false, fmt.Errorf("%s and %s_FILE are both set", name, name)
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+	return s, s != "", nil
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) Get() any           { return string(*v) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+func (v *intValue) Get() any { return int(*v) }
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*v = floatValue(f)
+	return nil
+}
+func (v *floatValue) Get() any { return float64(*v) }
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+func (v *boolValue) Get() any { return bool(*v) }
+
+// boolPtrValue is a boolean whose nil value means "use the default for Env".
+type boolPtrValue struct{ p **bool }
+
+func (v *boolPtrValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", s)
+	}
+	*v.p = &b
+	return nil
+}
+func (v *boolPtrValue) Get() any {
+	if *v.p == nil {
+		return nil
+	}
+	return **v.p
+}
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+func (v *durationValue) Get() any { return time.Duration(*v).String() }
+
+type listValue []string
+
+func (v *listValue) Set(s string) error {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*v = items
+	return nil
+}
+func (v *listValue) Get() any { return []string(*v) }
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Print writes the configuration as a YAML config file, with secrets masked,
+// keys in the order Load documents them and each line commented with the
+// environment variable that sets it.
+func (c *Config) Print(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	sections := map[string]*yaml.Node{}
+	for _, f := range c.Masked().fields() {
+		parent, name := root, f.key
+		if section, rest, ok := strings.Cut(f.key, "."); ok {
+			if sections[section] == nil {
+				sections[section] = &yaml.Node{Kind: yaml.MappingNode}
+				root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: section}, sections[section])
+			}
+			parent, name = sections[section], rest
+		}
+		var v yaml.Node
+		if err := v.Encode(f.v.Get()); err != nil {
+			return err
+		}
+		if v.Kind == yaml.SequenceNode {
+			v.Style = yaml.FlowStyle
+		}
+		v.LineComment = f.env
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, &v)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
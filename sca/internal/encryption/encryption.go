@@ -0,0 +1,179 @@
+// Package encryption seals target notes at rest with envelope encryption.
+// Each mission has a random data key that encrypts its notes with
+// AES-256-GCM; the data key is stored wrapped (AES-256-GCM again) by a
+// master key that only the process knows, so the database alone never
+// yields a usable key.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeySize is the size of master and data keys: AES-256.
+const KeySize = 32
+
+// ErrUnknownKey means a data key was wrapped by a master key that is not
+// in the keyring.
+var ErrUnknownKey = errors.New("unknown master key")
+
+// Keyring holds the master keys. The first one wraps new data keys; the
+// others only unwrap existing ones, which lets a rotation read what the
+// previous key wrote.
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// ParseKeyring reads base64-encoded 32-byte master keys separated by commas
+// or newlines, current key first. Lines starting with # are ignored, so a
+// keyfile can say which key is which. It returns nil for no keys.
+func ParseKeyring(s string) (*Keyring, error) {
+	var k *Keyring
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, item := range strings.Split(line, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, err := base64.StdEncoding.DecodeString(item)
+			if err != nil || len(key) != KeySize {
+				return nil, fmt.Errorf("master keys must be base64-encoded %d-byte keys", KeySize)
+			}
+			if k == nil {
+				k = &Keyring{current: KeyID(key), keys: map[string][]byte{}}
+			}
+			k.keys[KeyID(key)] = key
+		}
+	}
+	return k, nil
+}
+
+// KeyID names a master key without revealing it: the first 8 bytes of its
+// SHA-256 in hex.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// CurrentID names the master key that wraps new data keys.
+func (k *Keyring) CurrentID() string { return k.current }
+
+// Wrap encrypts dek with the current master key, binding it to aad.
+func (k *Keyring) Wrap(dek, aad []byte) (id string, wrapped []byte, err error) {
+	wrapped, err = seal(k.keys[k.current], dek, aad)
+	return k.current, wrapped, err
+}
+
+// Unwrap decrypts a data key wrapped by master key id with the same aad.
+func (k *Keyring) Unwrap(id string, wrapped, aad []byte) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownKey, id)
+	}
+	return open(key, wrapped, aad)
+}
+
+// NewDataKey returns a random data key.
+func NewDataKey() ([]byte, error) {
+	dek := make([]byte, KeySize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, err
+	}
+	return dek, nil
+}
+
+// sealedPrefix starts every sealed value; anything else is plaintext
+// written before encryption was enabled.
+const sealedPrefix = "enc:v1:"
+
+// Seal encrypts plaintext with data key dek, whose ID is recorded in the
+// result as "enc:v1:<id>:<base64 nonce and ciphertext>".
+func Seal(id uint, dek []byte, plaintext string) (string, error) {
+	header := sealedPrefix + strconv.FormatUint(uint64(id), 10) + ":"
+	ct, err := seal(dek, []byte(plaintext), []byte(header))
+	if err != nil {
+		return "", err
+	}
+	return header + base64.StdEncoding.EncodeToString(ct), nil
+}
+
+// Open decrypts a value sealed with data key dek.
+func Open(dek []byte, sealed string) (string, error) {
+	i := strings.LastIndexByte(sealed, ':')
+	if _, ok := DataKeyID(sealed); !ok || i < 0 {
+		return "", errors.New("not a sealed value")
+	}
+	ct, err := base64.StdEncoding.DecodeString(sealed[i+1:])
+	if err != nil {
+		return "", fmt.Errorf("malformed sealed value: %w", err)
+	}
+	pt, err := open(dek, ct, []byte(sealed[:i+1]))
+	return string(pt), err
+}
+
+// DataKeyID returns the ID of the data key s was sealed with, and false
+// when s is plaintext.
+func DataKeyID(s string) (uint, bool) {
+	rest, ok := strings.CutPrefix(s, sealedPrefix)
+	if !ok {
+		return 0, false
+	}
+	idText, _, ok := strings.Cut(rest, ":")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(idText, 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// IsSealed reports whether s was written by Seal.
+func IsSealed(s string) bool {
+	_, ok := DataKeyID(s)
+	return ok
+}
+
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(key, ciphertext, aad []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ct := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ct, aad)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
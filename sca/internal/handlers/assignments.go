@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"sca/sca/internal/assignment"
+	"sca/sca/internal/auth"
+	"sca/sca/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+type assignCatReq struct {
+	CatID  uint   `json:"cat_id" validate:"required"`
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+func (r assignCatReq) change(c *gin.Context) assignment.Change {
+	return change(c, r.Reason)
+}
+
+// change records the authenticated caller as the actor of an assignment
+// change.
+func change(c *gin.Context, reason string) assignment.Change {
+	return assignment.Change{Actor: auth.Subject(c.Request.Context()), Reason: reason}
+}
+
+// AssignCat godoc
+// @Summary Assign a cat to a mission
+// @Tags missions
+// @Accept json
+// @Produce json
+// @Param id path int true "Mission ID"
+// @Param payload body assignCatReq true "Cat assignment payload"
+// @Success 200 {object} models.Mission
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem "MISSION_NOT_FOUND, CAT_NOT_FOUND"
+// @Failure 409 {object} problem.Problem "MISSION_COMPLETED, MISSION_CLOSED, CAT_ALREADY_ACTIVE (with conflicting_mission_id), MISSION_NOT_EDITABLE, INVALID_TRANSITION"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id}/assign_cat [post]
+func (h *Handler) AssignCat(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	var req assignCatReq
+	if !h.bind(c, &req) {
+		return
+	}
+	m, err := h.assign.Assign(c.Request.Context(), id, req.CatID, req.change(c))
+	if err != nil {
+		problem.Error(c, missionErr(err))
+		return
+	}
+	c.JSON(200, m)
+}
+
+// UnassignCat godoc
+// @Summary Remove the cat from a mission
+// @Description Only assigned missions can lose their cat; they go back to draft. Active missions must be reassigned instead.
+// @Tags missions
+// @Produce json
+// @Param id path int true "Mission ID"
+// @Param reason query string false "Why the cat is removed"
+// @Success 200 {object} models.Mission
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "MISSION_NOT_ASSIGNED, MISSION_COMPLETED, MISSION_CLOSED, INVALID_TRANSITION"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id}/assign_cat [delete]
+func (h *Handler) UnassignCat(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	m, err := h.assign.Unassign(c.Request.Context(), id, change(c, c.Query("reason")))
+	if err != nil {
+		problem.Error(c, missionErr(err))
+		return
+	}
+	c.JSON(200, m)
+}
+
+// ReassignCat godoc
+// @Summary Hand a mission over to another cat
+// @Description Swaps the cat of an assigned or active mission in one transaction; the mission keeps its state.
+// @Tags missions
+// @Accept json
+// @Produce json
+// @Param id path int true "Mission ID"
+// @Param payload body assignCatReq true "New cat and why it takes over"
+// @Success 200 {object} models.Mission
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem "MISSION_NOT_FOUND, CAT_NOT_FOUND"
+// @Failure 409 {object} problem.Problem "MISSION_NOT_ASSIGNED, MISSION_COMPLETED, MISSION_CLOSED, CAT_ALREADY_ACTIVE (with conflicting_mission_id)"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id}/reassign_cat [post]
+func (h *Handler) ReassignCat(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	var req assignCatReq
+	if !h.bind(c, &req) {
+		return
+	}
+	m, err := h.assign.Reassign(c.Request.Context(), id, req.CatID, req.change(c))
+	if err != nil {
+		problem.Error(c, missionErr(err))
+		return
+	}
+	c.JSON(200, m)
+}
+
+// ListAssignments godoc
+// @Summary List a mission's cat hand-overs
+// @Tags missions
+// @Produce json
+// @Param id path int true "Mission ID"
+// @Success 200 {array} models.MissionAssignment
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id}/assignments [get]
+func (h *Handler) ListAssignments(c *gin.Context) {
+	m, ok := h.loadMission(c)
+	if !ok {
+		return
+	}
+	list, err := h.store.Missions().Assignments(c.Request.Context(), m.ID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(200, list)
+}
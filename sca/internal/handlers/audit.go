@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"sca/sca/internal/models"
+	"sca/sca/internal/problem"
+	"sca/sca/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type auditList struct {
+	Items      []models.AuditEvent `json:"items"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// ListAudit godoc
+// @Summary List audit events
+// @Description Every change to cats, missions and targets, with the fields it changed (notes are redacted). Cursor-paginated like the other lists; newest first with sort=-id.
+// @Tags audit
+// @Produce json
+// @Param limit query int false "Page size (1–200, default 50)"
+// @Param cursor query string false "Opaque cursor from a previous page"
+// @Param sort query string false "Sort field, prefix with - for descending" Enums(id, -id, created_at, -created_at)
+// @Param entity_type query string false "Only events on this kind of entity" Enums(cat, mission, target)
+// @Param entity_id query int false "Only events on this entity"
+// @Param actor query string false "Only events by this principal"
+// @Param created_after query string false "Recorded at or after (RFC 3339)"
+// @Param created_before query string false "Recorded before (RFC 3339)"
+// @Success 200 {object} auditList
+// @Header 200 {string} Link "Next page, rel=next"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /audit [get]
+func (h *Handler) ListAudit(c *gin.Context) {
+	page, err := pageParams(c)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	q := queryParser{c: c}
+	f := repository.AuditFilter{
+		EntityType:  c.Query("entity_type"),
+		EntityID:    q.Uint("entity_id"),
+		Actor:       c.Query("actor"),
+		CreatedFrom: q.Time("created_after"),
+		CreatedTo:   q.Time("created_before"),
+	}
+	if q.err != nil {
+		problem.Error(c, q.err)
+		return
+	}
+
+	events, next, err := h.store.Audit().List(c.Request.Context(), f, page)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	setNextLink(c, next)
+	c.JSON(200, auditList{Items: events, NextCursor: next})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"sca/sca/internal/breeds"
+	"sca/sca/internal/problem"
+	"sca/sca/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBreedQueryLen bounds each search parameter, since fuzzy matching
+// costs grow with the length of the query.
+const maxBreedQueryLen = 100
+
+// ListBreeds godoc
+// @Summary List or search cat breeds
+// @Description The local breed catalog, ordered by name. Each breed says when it was last synced and from where: `thecatapi`, `seed` (the list bundled with the server) or `file`.
+// @Description With `q`, `origin` or `temperament` only matching breeds are returned, best matches first. Matching ignores case and tolerates typos and partial words. Each parameter may be at most 100 characters long.
+// @Tags cats
+// @Produce json
+// @Param q query string false "Breed name, alternative name or ID"
+// @Param origin query string false "Country of origin or its code"
+// @Param temperament query string false "Comma-separated traits the breed must all have, e.g. playful,intelligent"
+// @Success 200 {array} models.Breed
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /breeds [get]
+func (h *Handler) ListBreeds(c *gin.Context) {
+	for _, key := range []string{"q", "origin", "temperament"} {
+		if n := len(strings.Join(c.QueryArray(key), ",")); n > maxBreedQueryLen {
+			problem.Write(c, problem.Newf(http.StatusBadRequest, problem.CodeValidation, "%s must be at most %d characters", key, maxBreedQueryLen))
+			return
+		}
+	}
+	q := breeds.Query{Q: c.Query("q"), Origin: c.Query("origin")}
+	for _, v := range c.QueryArray("temperament") {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				q.Temperament = append(q.Temperament, t)
+			}
+		}
+	}
+
+	list, err := h.store.Breeds().List(c.Request.Context())
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if !q.IsZero() {
+		list = breeds.Search(list, q)
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetBreed godoc
+// @Summary Get a cat breed
+// @Description Looks the breed up by ID or, ignoring case, by name.
+// @Tags cats
+// @Produce json
+// @Param id path string true "Breed ID or name"
+// @Success 200 {object} models.Breed
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem "BREED_NOT_FOUND"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /breeds/{id} [get]
+func (h *Handler) GetBreed(c *gin.Context) {
+	b, err := h.store.Breeds().Find(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		problem.Error(c, breedErr(err))
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+func breedErr(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.New(http.StatusNotFound, problem.CodeBreedNotFound, "breed not found")
+	}
+	return err
+}
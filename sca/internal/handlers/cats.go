@@ -1,28 +1,32 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
-	"time"
 
-	"sca/sca/internal/clients/thecatapi"
+	"sca/sca/internal/assignment"
+	"sca/sca/internal/audit"
+	"sca/sca/internal/auth"
 	"sca/sca/internal/models"
+	"sca/sca/internal/problem"
+	"sca/sca/internal/repository"
 
 	// "sca/sca/internal/validators"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"gorm.io/gorm"
 )
 
 type Handler struct {
-	db     *gorm.DB
-	v      *validator.Validate
-	breeds thecatapi.Client
+	store      repository.Store
+	v          *validator.Validate
+	completion CompletionPolicy
+	assign     *assignment.Service
 }
 
-func New(db *gorm.DB, opts ...Option) *Handler {
-	h := &Handler{db: db, v: validator.New(), breeds: thecatapi.NewHTTP(10 * time.Minute)}
+func New(store repository.Store, opts ...Option) *Handler {
+	h := &Handler{store: store, v: validator.New(), completion: DefaultCompletionPolicy, assign: assignment.New(store)}
+	h.v.RegisterTagNameFunc(problem.JSONTagName)
 	for _, o := range opts {
 		o(h)
 	}
@@ -31,8 +35,6 @@ func New(db *gorm.DB, opts ...Option) *Handler {
 
 type Option func(*Handler)
 
-func WithBreedClient(c thecatapi.Client) Option { return func(h *Handler) { h.breeds = c } }
-
 type createCatReq struct {
 	Name              string `json:"name" validate:"required,min=2"`
 	YearsOfExperience int    `json:"years_of_experience" validate:"gte=0"`
@@ -42,55 +44,113 @@ type createCatReq struct {
 
 // CreateCat godoc
 // @Summary Create a spy cat
+// @Description `breed` is a breed ID or name from `GET /breeds`, in any case. The cat stores the breed's ID and shows its name and origin in `breed_info`.
 // @Tags cats
 // @Accept json
 // @Produce json
 // @Param payload body createCatReq true "Cat payload"
 // @Success 201 {object} models.Cat
-// @Failure 400 {object} map[string]any
+// @Failure 400 {object} problem.Problem "VALIDATION_FAILED, INVALID_BREED"
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /cats [post]
 func (h *Handler) CreateCat(c *gin.Context) {
 	var req createCatReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	if err := h.v.Struct(req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !h.bind(c, &req) {
 		return
 	}
-	ok, err := h.breeds.ValidateBreed(req.Breed)
+	ctx := c.Request.Context()
+	breed, err := h.store.Breeds().Find(ctx, req.Breed)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "breed validation upstream unavailable"})
-		return
-	}
-	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid breed: " + req.Breed})
+		if errors.Is(err, repository.ErrNotFound) {
+			problem.Write(c, problem.New(http.StatusBadRequest, problem.CodeInvalidBreed, "invalid breed: "+req.Breed))
+			return
+		}
+		problem.Error(c, err)
 		return
 	}
 
-	cat := models.Cat{Name: req.Name, YearsOfExperience: req.YearsOfExperience, Breed: req.Breed, SalaryCents: req.SalaryCents}
-	if err := h.db.Create(&cat).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	// Store the canonical ID, whatever spelling was sent.
+	cat := models.Cat{Name: req.Name, YearsOfExperience: req.YearsOfExperience, Breed: breed.ID, SalaryCents: req.SalaryCents}
+	err = h.store.Tx(ctx, func(s repository.Store) error {
+		if err := s.Cats().Create(ctx, &cat); err != nil {
+			return err
+		}
+		return audit.Record(ctx, s, audit.ActionCreate, audit.EntityCat, cat.ID, nil, &cat)
+	})
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
+	cat.BreedInfo = breed.Summary()
 	c.JSON(http.StatusCreated, cat)
 }
 
+type catList struct {
+	Items      []models.Cat `json:"items"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
 // ListCats godoc
 // @Summary List spy cats
+// @Description Cursor-paginated. Pass next_cursor back as `cursor` (with the same `sort`) to get the following page; it is also sent as a `Link: <...>; rel="next"` header.
 // @Tags cats
 // @Produce json
-// @Success 200 {array} models.Cat
-// @Failure 500 {object} map[string]any
+// @Param limit query int false "Page size (1–200, default 50)"
+// @Param cursor query string false "Opaque cursor from a previous page"
+// @Param sort query string false "Sort field, prefix with - for descending" Enums(id, -id, name, -name, years_of_experience, -years_of_experience, salary_cents, -salary_cents, created_at, -created_at)
+// @Param breed query string false "Breed ID or name (case-insensitive)"
+// @Param min_experience query int false "Minimum years of experience"
+// @Param max_experience query int false "Maximum years of experience"
+// @Param min_salary_cents query int false "Minimum salary in cents"
+// @Param max_salary_cents query int false "Maximum salary in cents"
+// @Success 200 {object} catList
+// @Header 200 {string} Link "Next page, rel=next"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /cats [get]
 func (h *Handler) ListCats(c *gin.Context) {
-	var cats []models.Cat
-	if err := h.db.Find(&cats).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	page, err := pageParams(c)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	q := queryParser{c: c}
+	f := repository.CatFilter{
+		Breed:         c.Query("breed"),
+		MinExperience: q.Int("min_experience"),
+		MaxExperience: q.Int("max_experience"),
+		MinSalary:     q.Int64("min_salary_cents"),
+		MaxSalary:     q.Int64("max_salary_cents"),
+	}
+	if q.err != nil {
+		problem.Error(c, q.err)
+		return
+	}
+	if f.Breed != "" {
+		// An unknown breed stays as it is and matches no cat.
+		if b, err := h.store.Breeds().Find(c.Request.Context(), f.Breed); err == nil {
+			f.Breed = b.ID
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			problem.Error(c, err)
+			return
+		}
+	}
+
+	cats, next, err := h.store.Cats().List(c.Request.Context(), f, page)
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
-	c.JSON(200, cats)
+	setNextLink(c, next)
+	c.JSON(200, catList{Items: cats, NextCursor: next})
 }
 
 // GetCat godoc
@@ -99,33 +159,25 @@ func (h *Handler) ListCats(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Cat ID"
 // @Success 200 {object} models.Cat
-// @Failure 404 {object} map[string]any
-// @Failure 500 {object} map[string]any
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /cats/{id} [get]
 func (h *Handler) GetCat(c *gin.Context) {
-	id, _ := strconv.Atoi(c.Param("id"))
-	var cat models.Cat
-	if err := h.db.First(&cat, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "not found"})
+	id, ok := pathID(c, "id")
+	if !ok {
 		return
 	}
-	c.JSON(200, cat)
-}
-
-// ListBreeds godoc
-// @Summary Get all cat breeds from external API
-// @Tags cats
-// @Produce json
-// @Success 200 {array} thecatapi.Breed
-// @Failure 502 {object} map[string]any
-// @Router /breeds [get]
-func (h *Handler) ListBreeds(c *gin.Context) {
-	list, err := h.breeds.ListBreeds()
+	cat, err := h.store.Cats().Get(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "breed service unavailable"})
+		problem.Error(c, catErr(err))
 		return
 	}
-	c.JSON(http.StatusOK, list)
+	c.JSON(200, cat)
 }
 
 type updateCatReq struct {
@@ -134,41 +186,58 @@ type updateCatReq struct {
 
 // UpdateCat godoc
 // @Summary Update a spy cat
+// @Description Only the finance role may change salary_cents.
 // @Tags cats
 // @Accept json
 // @Produce json
 // @Param id path int true "Cat ID"
 // @Param payload body updateCatReq true "Update cat payload"
 // @Success 200 {object} models.Cat
-// @Failure 400 {object} map[string]any
-// @Failure 404 {object} map[string]any
-// @Failure 500 {object} map[string]any
-// @Router /cats/{id} [put]
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /cats/{id} [patch]
 func (h *Handler) UpdateCat(c *gin.Context) {
-	id, _ := strconv.Atoi(c.Param("id"))
-	var req updateCatReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	id, ok := pathID(c, "id")
+	if !ok {
 		return
 	}
-	if err := h.v.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	var req updateCatReq
+	if !h.bind(c, &req) {
 		return
 	}
 	updates := map[string]any{}
 	if req.SalaryCents != nil {
+		if !auth.Can(c.Request.Context(), auth.PermCatsSalary) {
+			problem.Write(c, auth.Forbidden("only finance may change salary_cents"))
+			return
+		}
 		updates["salary_cents"] = *req.SalaryCents
 	}
 	if len(updates) == 0 {
-		c.JSON(400, gin.H{"error": "no fields"})
+		problem.Write(c, problem.New(http.StatusBadRequest, problem.CodeNoFields, "no updatable fields in request"))
 		return
 	}
-	if err := h.db.Model(&models.Cat{}).Where("id = ?", id).Updates(updates).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	var cat *models.Cat
+	ctx := c.Request.Context()
+	err := h.store.Tx(ctx, func(s repository.Store) error {
+		before, err := s.Cats().GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if cat, err = s.Cats().Update(ctx, id, updates); err != nil {
+			return err
+		}
+		return audit.Record(ctx, s, audit.ActionUpdate, audit.EntityCat, id, before, cat)
+	})
+	if err != nil {
+		problem.Error(c, catErr(err))
 		return
 	}
-	var cat models.Cat
-	h.db.First(&cat, id)
 	c.JSON(200, cat)
 }
 
@@ -178,19 +247,42 @@ func (h *Handler) UpdateCat(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Cat ID"
 // @Success 204 "No Content"
-// @Failure 404 {object} map[string]any
-// @Failure 500 {object} map[string]any
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "CAT_HAS_MISSIONS"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /cats/{id} [delete]
 func (h *Handler) DeleteCat(c *gin.Context) {
-	id, _ := strconv.Atoi(c.Param("id"))
-	res := h.db.Delete(&models.Cat{}, id)
-	if res.Error != nil {
-		c.JSON(500, gin.H{"error": res.Error.Error()})
+	id, ok := pathID(c, "id")
+	if !ok {
 		return
 	}
-	if res.RowsAffected == 0 {
-		c.JSON(404, gin.H{"error": "not found"})
+	ctx := c.Request.Context()
+	err := h.store.Tx(ctx, func(s repository.Store) error {
+		before, err := s.Cats().GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.Cats().Delete(ctx, id); err != nil {
+			return err
+		}
+		return audit.Record(ctx, s, audit.ActionDelete, audit.EntityCat, id, before, nil)
+	})
+	if err != nil {
+		problem.Error(c, catErr(err))
 		return
 	}
 	c.Status(204)
 }
+
+// catErr reports a generic not-found from the cat repository as CAT_NOT_FOUND.
+func catErr(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return repository.ErrCatNotFound
+	}
+	return err
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"sca/sca/internal/audit"
+	"sca/sca/internal/auth"
+	"sca/sca/internal/models"
+	"sca/sca/internal/notes"
+	"sca/sca/internal/problem"
+	"sca/sca/internal/repository"
+)
+
+// CompletionPolicy decides how missions get completed.
+type CompletionPolicy struct {
+	// AutoComplete completes an active mission in the same transaction that
+	// completes or deletes its last open target.
+	AutoComplete bool
+	// RequireTargetsDone rejects manual completion while targets are open
+	// unless the request sets force=true and gives a reason.
+	RequireTargetsDone bool
+}
+
+// DefaultCompletionPolicy enables both rules.
+var DefaultCompletionPolicy = CompletionPolicy{AutoComplete: true, RequireTargetsDone: true}
+
+func WithCompletionPolicy(p CompletionPolicy) Option {
+	return func(h *Handler) { h.completion = p }
+}
+
+// autoCompleteReason is recorded on transitions made by AutoComplete.
+const autoCompleteReason = "all targets completed"
+
+// changeState locks mission id and moves it to state to. Manual completion
+// goes through the completion policy: with open targets it fails with
+// models.ErrTargetsOpen unless forced, and a forced completion is flagged on
+// the mission together with its reason.
+func (h *Handler) changeState(ctx context.Context, id uint, to models.MissionState, reason string, force bool) (*models.Mission, error) {
+	var m *models.Mission
+	err := h.store.Tx(ctx, func(s repository.Store) error {
+		var err error
+		if m, err = s.Missions().GetForUpdate(ctx, id); err != nil {
+			return missionErr(err)
+		}
+		if m.State.Terminal() {
+			return closedProblem(m)
+		}
+		before := *m
+		if to == models.StateCompleted && h.completion.RequireTargetsDone && m.OpenTargets() > 0 {
+			if !force {
+				return models.ErrTargetsOpen
+			}
+			m.CompletionForced, m.CompletionReason = true, reason
+		}
+		if err := transition(ctx, s, m, to, reason); err != nil {
+			return err
+		}
+		return audit.Record(ctx, s, audit.ActionTransition, audit.EntityMission, m.ID, &before, m)
+	})
+	return m, err
+}
+
+// updateTarget locks the mission, re-reads its target tid and lets edit
+// change that fresh copy, so a change committed since the handler read the
+// target isn't overwritten. It saves the result, adding a notes revision
+// when they changed, and, when the policy allows, completes the mission if
+// the target was its last open one.
+func (h *Handler) updateTarget(ctx context.Context, missionID, tid uint, edit func(t *models.Target) error) (*models.Target, error) {
+	var t *models.Target
+	err := h.store.Tx(ctx, func(s repository.Store) error {
+		m, before, err := lockTarget(ctx, s, missionID, tid)
+		if err != nil {
+			return err
+		}
+		after := *before
+		t = &after
+		if err := edit(t); err != nil {
+			return err
+		}
+		if err := s.Targets().Update(ctx, t); err != nil {
+			return err
+		}
+		if err := notes.Record(ctx, s, t, before.Notes, auth.Subject(ctx)); err != nil {
+			return err
+		}
+		if err := audit.Record(ctx, s, audit.ActionUpdate, audit.EntityTarget, t.ID, before, t); err != nil {
+			return err
+		}
+		if !t.Completed {
+			return nil
+		}
+		for i := range m.Targets {
+			if m.Targets[i].ID == t.ID {
+				m.Targets[i] = *t
+			}
+		}
+		return h.autoComplete(ctx, s, m)
+	})
+	return t, err
+}
+
+// deleteTarget deletes target tid under its locked mission; completed
+// targets stay. When the policy allows, the mission is completed if only
+// completed targets are left.
+func (h *Handler) deleteTarget(ctx context.Context, missionID, tid uint) error {
+	return h.store.Tx(ctx, func(s repository.Store) error {
+		m, t, err := lockTarget(ctx, s, missionID, tid)
+		if err != nil {
+			return err
+		}
+		if t.Completed {
+			return problem.New(http.StatusConflict, problem.CodeTargetCompleted, "cannot delete completed target")
+		}
+		if err := s.Targets().Delete(ctx, t.ID); err != nil {
+			return targetErr(err)
+		}
+		if err := audit.Record(ctx, s, audit.ActionDelete, audit.EntityTarget, t.ID, t, nil); err != nil {
+			return err
+		}
+		m.Targets = slices.DeleteFunc(m.Targets, func(o models.Target) bool { return o.ID == t.ID })
+		return h.autoComplete(ctx, s, m)
+	})
+}
+
+// lockTarget locks the open mission missionID and reads its target tid.
+func lockTarget(ctx context.Context, s repository.Store, missionID, tid uint) (*models.Mission, *models.Target, error) {
+	m, err := s.Missions().GetForUpdate(ctx, missionID)
+	if err != nil {
+		return nil, nil, missionErr(err)
+	}
+	if m.State.Terminal() {
+		return nil, nil, closedProblem(m)
+	}
+	t, err := s.Targets().Get(ctx, tid)
+	if err != nil {
+		return nil, nil, targetErr(err)
+	}
+	if t.MissionID != m.ID {
+		return nil, nil, problem.New(http.StatusNotFound, problem.CodeTargetNotInMission, "target not in mission")
+	}
+	return m, t, nil
+}
+
+// autoComplete completes the locked mission m when the policy allows, it
+// is active and none of its targets is open.
+func (h *Handler) autoComplete(ctx context.Context, s repository.Store, m *models.Mission) error {
+	if !h.completion.AutoComplete || m.State != models.StateActive || m.OpenTargets() > 0 {
+		return nil
+	}
+	open := *m
+	if err := transition(ctx, s, m, models.StateCompleted, autoCompleteReason); err != nil {
+		return err
+	}
+	return audit.Record(ctx, s, audit.ActionTransition, audit.EntityMission, m.ID, &open, m)
+}
@@ -0,0 +1,304 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sca/sca/internal/handlers"
+	"sca/sca/internal/models"
+	"sca/sca/internal/problem"
+	"sca/sca/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// api serves the handlers on a Memory store. No principal is set, so every
+// route is allowed.
+type api struct {
+	t *testing.T
+	r *gin.Engine
+}
+
+func newAPI(t *testing.T) *api {
+	t.Helper()
+	return newAPIOn(t, repository.NewMemory())
+}
+
+func newAPIOn(t *testing.T, store repository.Store) *api {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	breed := models.Breed{ID: "siam", Name: "Siamese", Source: models.BreedSourceSeed, SyncedAt: time.Now()}
+	if err := store.Breeds().Upsert(context.Background(), []models.Breed{breed}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := handlers.New(store)
+	r := gin.New()
+	v1 := r.Group("/api/v1")
+	v1.POST("/cats", h.CreateCat)
+	v1.GET("/breeds", h.ListBreeds)
+	v1.POST("/missions", h.CreateMission)
+	v1.GET("/missions/:id", h.GetMission)
+	v1.DELETE("/missions/:id", h.DeleteMission)
+	v1.POST("/missions/:id/transitions", h.TransitionMission)
+	v1.POST("/missions/:id/assign_cat", h.AssignCat)
+	v1.POST("/missions/:id/targets", h.AddTargets)
+	v1.PATCH("/missions/:id/targets/:tid", h.UpdateTarget)
+	v1.DELETE("/missions/:id/targets/:tid", h.DeleteTarget)
+	return &api{t: t, r: r}
+}
+
+// do sends body as JSON and decodes a non-empty response into out when it
+// is set.
+func (a *api) do(method, path string, body, out any) int {
+	a.t.Helper()
+	b, err := json.Marshal(body)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, "/api/v1"+path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	a.r.ServeHTTP(w, req)
+	if out != nil && w.Body.Len() > 0 {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			a.t.Fatalf("%s %s: decoding %q: %v", method, path, w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+// must is do that fails the test unless the response has status want.
+func (a *api) must(want int, method, path string, body, out any) {
+	a.t.Helper()
+	var raw json.RawMessage
+	if got := a.do(method, path, body, &raw); got != want {
+		a.t.Fatalf("%s %s: status %d, want %d: %s", method, path, got, want, raw)
+	}
+	if out != nil && raw != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			a.t.Fatal(err)
+		}
+	}
+}
+
+// fails checks that the request is answered with the given problem.
+func (a *api) fails(status int, code problem.Code, method, path string, body any) problem.Problem {
+	a.t.Helper()
+	var p problem.Problem
+	if got := a.do(method, path, body, &p); got != status || p.Code != code {
+		a.t.Fatalf("%s %s: got %d %s, want %d %s", method, path, got, p.Code, status, code)
+	}
+	return p
+}
+
+func (a *api) cat() uint {
+	a.t.Helper()
+	var cat models.Cat
+	a.must(http.StatusCreated, "POST", "/cats", gin.H{"name": "Tom", "breed": "Siamese", "salary_cents": 100}, &cat)
+	return cat.ID
+}
+
+func (a *api) mission(catID *uint, names ...string) models.Mission {
+	a.t.Helper()
+	var m models.Mission
+	a.must(http.StatusCreated, "POST", "/missions", gin.H{"assigned_cat_id": catID, "targets": targets(names...)}, &m)
+	return m
+}
+
+func targets(names ...string) []gin.H {
+	var out []gin.H
+	for _, n := range names {
+		out = append(out, gin.H{"name": n, "country": "UA"})
+	}
+	return out
+}
+
+func TestOneActiveMissionPerCat(t *testing.T) {
+	a := newAPI(t)
+	cat := a.cat()
+	first := a.mission(&cat, "alpha")
+	if first.State != models.StateAssigned {
+		t.Fatalf("state %s, want assigned", first.State)
+	}
+
+	p := a.fails(http.StatusConflict, problem.CodeCatAlreadyActive, "POST", "/missions",
+		gin.H{"assigned_cat_id": cat, "targets": targets("beta")})
+	if p.ConflictingMissionID != first.ID {
+		t.Fatalf("conflicting_mission_id %d, want %d", p.ConflictingMissionID, first.ID)
+	}
+
+	draft := a.mission(nil, "gamma")
+	p = a.fails(http.StatusConflict, problem.CodeCatAlreadyActive, "POST",
+		fmt.Sprintf("/missions/%d/assign_cat", draft.ID), gin.H{"cat_id": cat})
+	if p.ConflictingMissionID != first.ID {
+		t.Fatalf("conflicting_mission_id %d, want %d", p.ConflictingMissionID, first.ID)
+	}
+}
+
+func TestAtMostThreeTargets(t *testing.T) {
+	a := newAPI(t)
+	a.fails(http.StatusBadRequest, problem.CodeValidation, "POST", "/missions",
+		gin.H{"targets": targets("a1", "a2", "a3", "a4")})
+
+	m := a.mission(nil, "a1", "a2")
+	path := fmt.Sprintf("/missions/%d/targets", m.ID)
+	a.fails(http.StatusConflict, problem.CodeTooManyTargets, "POST", path, gin.H{"targets": targets("a3", "a4")})
+	a.must(http.StatusOK, "POST", path, gin.H{"targets": targets("a3")}, nil)
+	a.fails(http.StatusConflict, problem.CodeTooManyTargets, "POST", path, gin.H{"targets": targets("a4")})
+
+	var got models.Mission
+	a.must(http.StatusOK, "GET", fmt.Sprintf("/missions/%d", m.ID), nil, &got)
+	if len(got.Targets) != 3 {
+		t.Fatalf("%d targets, want 3", len(got.Targets))
+	}
+}
+
+func TestUniqueTargetNames(t *testing.T) {
+	a := newAPI(t)
+	a.fails(http.StatusConflict, problem.CodeDuplicateTarget, "POST", "/missions",
+		gin.H{"targets": targets("alpha", "alpha")})
+
+	m := a.mission(nil, "alpha")
+	path := fmt.Sprintf("/missions/%d/targets", m.ID)
+	a.fails(http.StatusConflict, problem.CodeDuplicateTarget, "POST", path, gin.H{"targets": targets("alpha")})
+	a.fails(http.StatusConflict, problem.CodeDuplicateTarget, "POST", path, gin.H{"targets": targets("beta", "beta")})
+
+	// Another mission may reuse the name.
+	a.mission(nil, "alpha")
+}
+
+func TestCompletedTargetNotesFrozen(t *testing.T) {
+	a := newAPI(t)
+	m := a.mission(nil, "alpha", "beta")
+	path := fmt.Sprintf("/missions/%d/targets/%d", m.ID, m.Targets[0].ID)
+
+	a.must(http.StatusOK, "PATCH", path, gin.H{"notes": "seen at the docks"}, nil)
+	a.must(http.StatusOK, "PATCH", path, gin.H{"completed": true}, nil)
+	a.fails(http.StatusConflict, problem.CodeTargetNotesFrozen, "PATCH", path, gin.H{"notes": "left town"})
+
+	var got models.Mission
+	a.must(http.StatusOK, "GET", fmt.Sprintf("/missions/%d", m.ID), nil, &got)
+	if n := got.Targets[0].Notes; n != "seen at the docks" {
+		t.Fatalf("notes %q after a frozen update", n)
+	}
+}
+
+func TestDeleteMission(t *testing.T) {
+	a := newAPI(t)
+	cat := a.cat()
+	m := a.mission(&cat, "alpha")
+	path := fmt.Sprintf("/missions/%d", m.ID)
+	a.fails(http.StatusConflict, problem.CodeMissionAssigned, "DELETE", path, nil)
+
+	// Completing the last target of the active mission completes it too.
+	a.must(http.StatusOK, "POST", path+"/transitions", gin.H{"to": "active"}, nil)
+	a.must(http.StatusOK, "PATCH", fmt.Sprintf("%s/targets/%d", path, m.Targets[0].ID), gin.H{"completed": true}, nil)
+	var got models.Mission
+	a.must(http.StatusOK, "GET", path, nil, &got)
+	if got.State != models.StateCompleted || got.AssignedCatID == nil {
+		t.Fatalf("state %s, cat %v; want completed with the cat kept", got.State, got.AssignedCatID)
+	}
+
+	a.must(http.StatusNoContent, "DELETE", path, nil, nil)
+	a.fails(http.StatusNotFound, problem.CodeMissionNotFound, "GET", path, nil)
+}
+
+func TestNotesLengthLimit(t *testing.T) {
+	a := newAPI(t)
+	long := strings.Repeat("x", 10001)
+	a.fails(http.StatusBadRequest, problem.CodeValidation, "POST", "/missions",
+		gin.H{"targets": []gin.H{{"name": "alpha", "country": "UA", "notes": long}}})
+
+	m := a.mission(nil, "alpha")
+	path := fmt.Sprintf("/missions/%d/targets/%d", m.ID, m.Targets[0].ID)
+	a.fails(http.StatusBadRequest, problem.CodeValidation, "PATCH", path, gin.H{"notes": long})
+	a.must(http.StatusOK, "PATCH", path, gin.H{"notes": long[1:]}, nil)
+}
+
+func TestBreedSearchLengthLimit(t *testing.T) {
+	a := newAPI(t)
+	var list []models.Breed
+	a.must(http.StatusOK, "GET", "/breeds?q=siames", nil, &list)
+	if len(list) != 1 || list[0].ID != "siam" {
+		t.Fatalf("got %+v", list)
+	}
+	for _, key := range []string{"q", "origin", "temperament"} {
+		a.fails(http.StatusBadRequest, problem.CodeValidation, "GET", "/breeds?"+key+"="+strings.Repeat("x", 101), nil)
+	}
+	a.fails(http.StatusBadRequest, problem.CodeValidation, "GET",
+		"/breeds?temperament="+strings.Repeat("x", 60)+"&temperament="+strings.Repeat("y", 60), nil)
+}
+
+// racingStore runs race right before the next transaction starts, like a
+// concurrent request that commits first.
+type racingStore struct {
+	repository.Store
+	race func()
+}
+
+func (s *racingStore) Tx(ctx context.Context, fn func(s repository.Store) error) error {
+	if race := s.race; race != nil {
+		s.race = nil
+		race()
+	}
+	return s.Store.Tx(ctx, fn)
+}
+
+func TestUpdateTargetSeesConcurrentCompletion(t *testing.T) {
+	store := &racingStore{Store: repository.NewMemory()}
+	a := newAPIOn(t, store)
+	m := a.mission(nil, "alpha", "beta")
+	target := m.Targets[0]
+	path := fmt.Sprintf("/missions/%d/targets/%d", m.ID, target.ID)
+
+	store.race = func() {
+		done := target
+		done.Completed = true
+		if err := store.Store.Targets().Update(context.Background(), &done); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.fails(http.StatusConflict, problem.CodeTargetNotesFrozen, "PATCH", path, gin.H{"notes": ""})
+
+	var got models.Mission
+	a.must(http.StatusOK, "GET", fmt.Sprintf("/missions/%d", m.ID), nil, &got)
+	if !got.Targets[0].Completed {
+		t.Fatal("the concurrent completion was overwritten")
+	}
+}
+
+func TestDeleteTarget(t *testing.T) {
+	a := newAPI(t)
+	cat := a.cat()
+	m := a.mission(&cat, "alpha", "beta")
+	path := fmt.Sprintf("/missions/%d", m.ID)
+	alpha := fmt.Sprintf("%s/targets/%d", path, m.Targets[0].ID)
+	beta := fmt.Sprintf("%s/targets/%d", path, m.Targets[1].ID)
+	a.must(http.StatusOK, "POST", path+"/transitions", gin.H{"to": "active"}, nil)
+	a.must(http.StatusOK, "PATCH", alpha, gin.H{"completed": true}, nil)
+	a.fails(http.StatusConflict, problem.CodeTargetCompleted, "DELETE", alpha, nil)
+
+	// Deleting the last open target completes the active mission.
+	a.must(http.StatusNoContent, "DELETE", beta, nil, nil)
+	var got models.Mission
+	a.must(http.StatusOK, "GET", path, nil, &got)
+	if got.State != models.StateCompleted || len(got.Targets) != 1 {
+		t.Fatalf("state %s with %d targets, want completed with 1", got.State, len(got.Targets))
+	}
+
+	aborted := a.mission(nil, "gamma")
+	apath := fmt.Sprintf("/missions/%d", aborted.ID)
+	a.must(http.StatusOK, "POST", apath+"/transitions", gin.H{"to": "aborted"}, nil)
+	a.fails(http.StatusConflict, problem.CodeMissionClosed, "DELETE", fmt.Sprintf("%s/targets/%d", apath, aborted.Targets[0].ID), nil)
+
+	other := a.mission(nil, "delta")
+	a.fails(http.StatusNotFound, problem.CodeTargetNotInMission, "DELETE", fmt.Sprintf("/missions/%d/targets/%d", other.ID, aborted.Targets[0].ID), nil)
+}
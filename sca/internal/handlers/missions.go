@@ -1,85 +1,129 @@
 package handlers
 
 import (
-	"strconv"
+	"context"
+	"errors"
+	"net/http"
+	"time"
 
+	"sca/sca/internal/audit"
+	"sca/sca/internal/auth"
 	"sca/sca/internal/models"
+	"sca/sca/internal/notes"
+	"sca/sca/internal/problem"
+	"sca/sca/internal/repository"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 )
 
 type createMissionReq struct {
 	AssignedCatID *uint           `json:"assigned_cat_id"`
-	Completed     *bool           `json:"completed"`
 	Targets       []targetPayload `json:"targets" validate:"required,min=1,max=3,dive"`
 }
 type targetPayload struct {
 	Name      string `json:"name" validate:"required,min=2"`
 	Country   string `json:"country" validate:"required"`
-	Notes     string `json:"notes"`
+	Notes     string `json:"notes" validate:"max=10000"`
 	Completed bool   `json:"completed"`
 }
 
 // @Summary Create mission with targets
+// @Description The mission starts as draft, or as assigned when assigned_cat_id is given.
 // @Tags missions
 // @Accept json
 // @Produce json
 // @Param payload body createMissionReq true "Mission payload"
 // @Success 201 {object} models.Mission
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem "CAT_NOT_FOUND"
+// @Failure 409 {object} problem.Problem "CAT_ALREADY_ACTIVE (with conflicting_mission_id), DUPLICATE_TARGET_NAME"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /missions [post]
 func (h *Handler) CreateMission(c *gin.Context) {
 	var req createMissionReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !h.bind(c, &req) {
 		return
 	}
-	if err := h.v.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-	// build mission
-	completed := false
-	if req.Completed != nil {
-		completed = *req.Completed
-	}
-	m := models.Mission{Completed: completed}
-	if req.AssignedCatID != nil {
-		m.AssignedCatID = req.AssignedCatID
-	}
+	m := models.Mission{AssignedCatID: req.AssignedCatID}
 
 	// ensure unique target names within payload and build targets
 	seen := map[string]struct{}{}
 	for _, t := range req.Targets {
 		if _, ok := seen[t.Name]; ok {
-			c.JSON(400, gin.H{"error": "duplicate target name in request: " + t.Name})
+			problem.Write(c, problem.New(http.StatusConflict, problem.CodeDuplicateTarget, "duplicate target name in request: "+t.Name))
 			return
 		}
 		seen[t.Name] = struct{}{}
 		m.Targets = append(m.Targets, models.Target{Name: t.Name, Country: t.Country, Notes: t.Notes, Completed: t.Completed})
 	}
 
-	if err := h.db.Create(&m).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	if err := h.assign.Create(c.Request.Context(), &m, change(c, "")); err != nil {
+		problem.Error(c, err)
 		return
 	}
 	c.JSON(201, m)
 }
 
+type missionList struct {
+	Items      []models.Mission `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
 // ListMissions godoc
 // @Summary List missions with targets
+// @Description Cursor-paginated. Pass next_cursor back as `cursor` (with the same `sort`) to get the following page; it is also sent as a `Link: <...>; rel="next"` header. Callers with only the cat role see just the missions assigned to their cat.
 // @Tags missions
 // @Produce json
-// @Success 200 {array} models.Mission
-// @Failure 500 {object} map[string]any
+// @Param limit query int false "Page size (1–200, default 50)"
+// @Param cursor query string false "Opaque cursor from a previous page"
+// @Param sort query string false "Sort field, prefix with - for descending" Enums(id, -id, created_at, -created_at, updated_at, -updated_at)
+// @Param state query string false "Only missions in these states (comma-separated: draft, assigned, active, completed, aborted, failed)"
+// @Param assigned_cat_id query int false "Only missions assigned to this cat"
+// @Param country query string false "Only missions with a target in this country"
+// @Param created_after query string false "Created at or after (RFC 3339)"
+// @Param created_before query string false "Created before (RFC 3339)"
+// @Success 200 {object} missionList
+// @Header 200 {string} Link "Next page, rel=next"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /missions [get]
 func (h *Handler) ListMissions(c *gin.Context) {
-	var m []models.Mission
-	if err := h.db.Preload("Targets").Find(&m).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	page, err := pageParams(c)
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
-	c.JSON(200, m)
+	q := queryParser{c: c}
+	f := repository.MissionFilter{
+		States:        q.States("state"),
+		AssignedCatID: q.Uint("assigned_cat_id"),
+		TargetCountry: c.Query("country"),
+		CreatedFrom:   q.Time("created_after"),
+		CreatedTo:     q.Time("created_before"),
+	}
+	if q.err != nil {
+		problem.Error(c, q.err)
+		return
+	}
+	if cat, own := auth.OwnCat(c.Request.Context()); own {
+		f.AssignedCatID = &cat
+	}
+
+	m, next, err := h.store.Missions().List(c.Request.Context(), f, page)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	setNextLink(c, next)
+	c.JSON(200, missionList{Items: m, NextCursor: next})
 }
 
 // GetMission godoc
@@ -88,20 +132,17 @@ func (h *Handler) ListMissions(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Mission ID"
 // @Success 200 {object} models.Mission
-// @Failure 404 {object} map[string]any
-// @Failure 500 {object} map[string]any
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /missions/{id} [get]
 func (h *Handler) GetMission(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		c.JSON(400, gin.H{"error": "invalid id"})
-		return
-	}
-
-	var m models.Mission
-	if err := h.db.Preload("Targets").First(&m, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "not found"})
+	m, ok := h.loadMission(c)
+	if !ok {
 		return
 	}
 	c.JSON(200, m)
@@ -109,148 +150,171 @@ func (h *Handler) GetMission(c *gin.Context) {
 
 type updateMissionReq struct {
 	Completed *bool `json:"completed"`
+	// Force completes the mission even though targets are still open.
+	Force  bool   `json:"force"`
+	Reason string `json:"reason" validate:"required_with=Force,max=500"`
 }
 
 // UpdateMission godoc
 // @Summary Update a mission (mark as completed)
+// @Description `{"completed": true}` is a shortcut for the active → completed transition. While targets are open it fails with TARGETS_OPEN unless `force` is true and a `reason` is given; forced completions are flagged on the mission.
 // @Tags missions
 // @Accept json
 // @Produce json
 // @Param id path int true "Mission ID"
 // @Param payload body updateMissionReq true "Update mission payload"
 // @Success 200 {object} models.Mission
-// @Failure 400 {object} map[string]any
-// @Failure 404 {object} map[string]any
-// @Failure 500 {object} map[string]any
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "MISSION_COMPLETED, MISSION_CLOSED, INVALID_TRANSITION, TARGETS_OPEN"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /missions/{id} [patch]
 func (h *Handler) UpdateMission(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		c.JSON(400, gin.H{"error": "invalid id"})
-		return
-	}
-
-	var m models.Mission
-	if err := h.db.Preload("Targets").First(&m, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "not found"})
+	m, ok := h.loadMission(c)
+	if !ok {
 		return
 	}
-	if m.Completed {
-		c.JSON(400, gin.H{"error": "mission already completed"})
+	if m.State.Terminal() {
+		problem.Write(c, closedProblem(m))
 		return
 	}
 	var req updateMissionReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !h.bind(c, &req) {
 		return
 	}
 	if req.Completed != nil && *req.Completed {
-		m.Completed = true
-		if err := h.db.Save(&m).Error; err != nil {
-			c.JSON(500, gin.H{"error": err.Error()})
+		var err error
+		if m, err = h.changeState(c.Request.Context(), m.ID, models.StateCompleted, req.Reason, req.Force); err != nil {
+			problem.Error(c, err)
 			return
 		}
 	}
 	c.JSON(200, m)
 }
 
-// DeleteMission godoc
-// @Summary Delete a mission
+type transitionReq struct {
+	To models.MissionState `json:"to" validate:"required,oneof=draft assigned active completed aborted failed"`
+	// Force completes the mission even though targets are still open.
+	Force  bool   `json:"force"`
+	Reason string `json:"reason" validate:"required_with=Force,max=500"`
+}
+
+// TransitionMission godoc
+// @Summary Move a mission to another lifecycle state
+// @Description Allowed: draft → assigned | aborted; assigned → active | aborted | draft (only via DELETE /missions/{id}/assign_cat); active → completed | aborted | failed. Entering assigned or active requires an assigned cat. Completing with open targets fails with TARGETS_OPEN unless `force` is true and a `reason` is given.
 // @Tags missions
+// @Accept json
 // @Produce json
 // @Param id path int true "Mission ID"
-// @Success 204 "No Content"
-// @Failure 400 {object} map[string]any
-// @Failure 404 {object} map[string]any
-// @Failure 500 {object} map[string]any
-// @Router /missions/{id} [delete]
-func (h *Handler) DeleteMission(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		c.JSON(400, gin.H{"error": "invalid id"})
-		return
-	}
-
-	var m models.Mission
-	if err := h.db.First(&m, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "not found"})
+// @Param payload body transitionReq true "Target state and optional reason"
+// @Success 200 {object} models.Mission
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "INVALID_TRANSITION, CAT_REQUIRED, TARGETS_OPEN, MISSION_COMPLETED, MISSION_CLOSED"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id}/transitions [post]
+func (h *Handler) TransitionMission(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
 		return
 	}
-	if m.AssignedCatID != nil {
-		c.JSON(400, gin.H{"error": "cannot delete: assigned to a cat"})
+	var req transitionReq
+	if !h.bind(c, &req) {
 		return
 	}
-	if err := h.db.Delete(&m).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	m, err := h.changeState(c.Request.Context(), id, req.To, req.Reason, req.Force)
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
-	c.Status(204)
+	c.JSON(200, m)
 }
 
-type assignCatReq struct {
-	CatID uint `json:"cat_id" validate:"required"`
+// ListTransitions godoc
+// @Summary List a mission's state history
+// @Tags missions
+// @Produce json
+// @Param id path int true "Mission ID"
+// @Success 200 {array} models.MissionTransition
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id}/transitions [get]
+func (h *Handler) ListTransitions(c *gin.Context) {
+	m, ok := h.loadMission(c)
+	if !ok {
+		return
+	}
+	list, err := h.store.Missions().Transitions(c.Request.Context(), m.ID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(200, list)
 }
 
-// AssignCat godoc
-// @Summary Assign a cat to a mission
+// DeleteMission godoc
+// @Summary Delete a mission
+// @Description Open missions with a cat assigned can't be deleted; remove the cat first. Completed, aborted and failed missions can be deleted whatever their cat, and the audit log keeps the deleted mission.
 // @Tags missions
-// @Accept json
 // @Produce json
 // @Param id path int true "Mission ID"
-// @Param payload body assignCatReq true "Cat assignment payload"
-// @Success 200 {object} models.Mission
-// @Failure 400 {object} map[string]any
-// @Failure 404 {object} map[string]any
-// @Failure 500 {object} map[string]any
-// @Router /missions/{id}/assign_cat [post]
-func (h *Handler) AssignCat(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		c.JSON(400, gin.H{"error": "invalid id"})
-		return
-	}
-
-	var m models.Mission
-	if err := h.db.Preload("Targets").First(&m, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "not found"})
-		return
-	}
-	if m.Completed {
-		c.JSON(400, gin.H{"error": "mission completed"})
-		return
-	}
-
-	var req assignCatReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-	if err := h.v.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
-	m.AssignedCatID = &req.CatID
-	res := h.db.Model(&models.Mission{}).
-		Where("id = ? AND completed = false AND assigned_cat_id IS NULL", id).
-		Update("assigned_cat_id", req.CatID)
-	if res.Error != nil {
-		c.JSON(500, gin.H{"error": res.Error.Error()})
-		return
-	}
-	if res.RowsAffected == 0 {
-		c.JSON(409, gin.H{"error": "cat already assigned to an active mission or mission not editable"})
+// @Success 204 "No Content"
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "MISSION_ASSIGNED"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id} [delete]
+func (h *Handler) DeleteMission(c *gin.Context) {
+	m, ok := h.loadMission(c)
+	if !ok {
 		return
 	}
-
-	if err := h.db.Preload("Targets").First(&m, id).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	ctx := c.Request.Context()
+	err := h.store.Tx(ctx, func(s repository.Store) error {
+		var err error
+		if m, err = s.Missions().GetForUpdate(ctx, m.ID); err != nil {
+			return err
+		}
+		// A closed mission keeps its cat for the record, but the cat is
+		// free again; only an open mission still needs it.
+		if m.AssignedCatID != nil && !m.State.Terminal() {
+			return problem.New(http.StatusConflict, problem.CodeMissionAssigned, "cannot delete: assigned to a cat")
+		}
+		if err := s.Missions().Delete(ctx, m.ID); err != nil {
+			return err
+		}
+		if err := audit.Record(ctx, s, audit.ActionDelete, audit.EntityMission, m.ID, m, nil); err != nil {
+			return err
+		}
+		for i := range m.Targets {
+			if err := audit.Record(ctx, s, audit.ActionDelete, audit.EntityTarget, m.Targets[i].ID, &m.Targets[i], nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		problem.Error(c, missionErr(err))
 		return
 	}
-	c.JSON(200, m)
+	c.Status(204)
 }
 
 type addTargetsReq struct {
@@ -265,40 +329,32 @@ type addTargetsReq struct {
 // @Param id path int true "Mission ID"
 // @Param payload body addTargetsReq true "Targets payload (1–3 targets)"
 // @Success 200 {object} models.Mission
-// @Failure 400 {object} map[string]any
-// @Failure 404 {object} map[string]any
-// @Failure 500 {object} map[string]any
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "MISSION_COMPLETED, MISSION_CLOSED, TOO_MANY_TARGETS, DUPLICATE_TARGET_NAME"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /missions/{id}/targets [post]
 func (h *Handler) AddTargets(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		c.JSON(400, gin.H{"error": "invalid id"})
+	m, ok := h.loadMission(c)
+	if !ok {
 		return
 	}
-
-	var m models.Mission
-	if err := h.db.Preload("Targets").First(&m, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "not found"})
-		return
-	}
-	if m.Completed {
-		c.JSON(400, gin.H{"error": "mission completed"})
+	if m.State.Terminal() {
+		problem.Write(c, closedProblem(m))
 		return
 	}
 
 	var req addTargetsReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-	if err := h.v.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !h.bind(c, &req) {
 		return
 	}
 
-	if len(m.Targets)+len(req.Targets) > 3 {
-		c.JSON(400, gin.H{"error": "exceeds max 3 targets"})
+	if len(m.Targets)+len(req.Targets) > repository.MaxTargetsPerMission {
+		problem.Error(c, repository.ErrTooManyTargets)
 		return
 	}
 
@@ -307,36 +363,60 @@ func (h *Handler) AddTargets(c *gin.Context) {
 		existing[et.Name] = struct{}{}
 	}
 	reqSeen := map[string]struct{}{}
+	var added []models.Target
 	for _, t := range req.Targets {
-		if verr := validator.New().Var(t.Name, "required,min=2"); verr != nil {
-			c.JSON(400, gin.H{"error": "invalid target"})
-			return
-		}
 		if _, ok := existing[t.Name]; ok {
-			c.JSON(400, gin.H{"error": "target with this name already exists in mission: " + t.Name})
+			problem.Write(c, problem.New(http.StatusConflict, problem.CodeDuplicateTarget, "target with this name already exists in mission: "+t.Name))
 			return
 		}
 		if _, ok := reqSeen[t.Name]; ok {
-			c.JSON(400, gin.H{"error": "duplicate target name in request: " + t.Name})
+			problem.Write(c, problem.New(http.StatusConflict, problem.CodeDuplicateTarget, "duplicate target name in request: "+t.Name))
 			return
 		}
 		reqSeen[t.Name] = struct{}{}
-		m.Targets = append(m.Targets, models.Target{Name: t.Name, Country: t.Country, Notes: t.Notes, Completed: t.Completed})
+		added = append(added, models.Target{Name: t.Name, Country: t.Country, Notes: t.Notes, Completed: t.Completed})
+	}
+	ctx := c.Request.Context()
+	err := h.store.Tx(ctx, func(s repository.Store) error {
+		// Lock the mission like every other notes change, which keeps a
+		// concurrent key rotation from dropping the data key in use.
+		if _, err := s.Missions().GetForUpdate(ctx, m.ID); err != nil {
+			return err
+		}
+		created, err := s.Targets().Add(ctx, m.ID, added)
+		if err != nil {
+			return err
+		}
+		for i := range created {
+			if err := audit.Record(ctx, s, audit.ActionCreate, audit.EntityTarget, created[i].ID, nil, &created[i]); err != nil {
+				return err
+			}
+			if err := notes.Record(ctx, s, &created[i], "", auth.Subject(ctx)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		problem.Error(c, missionErr(err))
+		return
 	}
-	if err := h.db.Save(&m).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	m, err = h.store.Missions().Get(ctx, m.ID)
+	if err != nil {
+		problem.Error(c, missionErr(err))
 		return
 	}
 	c.JSON(200, m)
 }
 
 type updateTargetReq struct {
-	Notes     *string `json:"notes"`
+	Notes     *string `json:"notes" validate:"omitempty,max=10000"`
 	Completed *bool   `json:"completed"`
 }
 
 // UpdateTarget godoc
 // @Summary Update a target in a mission
+// @Description Completing the last open target of an active mission also completes the mission (unless auto-completion is disabled). Callers with only the cat role may update targets of their own active mission.
 // @Tags missions
 // @Accept json
 // @Produce json
@@ -344,66 +424,56 @@ type updateTargetReq struct {
 // @Param tid path int true "Target ID"
 // @Param payload body updateTargetReq true "Update target payload"
 // @Success 200 {object} models.Target
-// @Failure 400 {object} map[string]any
-// @Failure 404 {object} map[string]any
-// @Failure 500 {object} map[string]any
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem "MISSION_NOT_FOUND, TARGET_NOT_FOUND"
+// @Failure 409 {object} problem.Problem "MISSION_COMPLETED, MISSION_CLOSED, TARGET_NOTES_FROZEN"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /missions/{id}/targets/{tid} [patch]
 func (h *Handler) UpdateTarget(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		c.JSON(400, gin.H{"error": "invalid id"})
+	m, ok := h.loadMission(c)
+	if !ok {
 		return
 	}
-
-	var m models.Mission
-	if err := h.db.First(&m, id).Error; err != nil {
-		c.JSON(404, gin.H{"error": "mission not found"})
+	if m.State.Terminal() {
+		problem.Write(c, closedProblem(m))
 		return
 	}
-	if m.Completed {
-		c.JSON(400, gin.H{"error": "mission completed"})
+	if !auth.Can(c.Request.Context(), auth.PermTargetsWrite) && m.State != models.StateActive {
+		problem.Write(c, auth.Forbidden("targets can only be updated on your active mission"))
 		return
 	}
 
-	tid, _ := strconv.Atoi(c.Param("tid"))
-	var t models.Target
-	if err := h.db.First(&t, tid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "target not found"})
-		return
-	}
-	if t.MissionID != uint(id) {
-		c.JSON(400, gin.H{"error": "target not in mission"})
+	tid, ok := pathID(c, "tid")
+	if !ok {
 		return
 	}
-	if t.Completed {
-		c.JSON(400, gin.H{"error": "target completed; notes frozen"})
-		return
-	}
-
 	var req updateTargetReq
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !h.bind(c, &req) {
 		return
 	}
 	if req.Completed != nil && *req.Completed && req.Notes != nil {
-		c.JSON(400, gin.H{"error": "cannot update notes when marking target completed"})
+		problem.Write(c, problem.New(http.StatusBadRequest, problem.CodeValidation, "cannot update notes when marking target completed"))
 		return
 	}
 
-	if t.Completed {
-		c.JSON(400, gin.H{"error": "target completed; notes frozen"})
-		return
-	}
-
-	if req.Completed != nil && *req.Completed {
-		t.Completed = true
-	}
-	if req.Notes != nil {
-		t.Notes = *req.Notes
-	}
-	if err := h.db.Save(&t).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+	t, err := h.updateTarget(c.Request.Context(), m.ID, tid, func(t *models.Target) error {
+		if t.Completed {
+			return problem.New(http.StatusConflict, problem.CodeTargetNotesFrozen, "target completed; notes frozen")
+		}
+		if req.Completed != nil && *req.Completed {
+			t.Completed = true
+		}
+		if req.Notes != nil {
+			t.Notes = *req.Notes
+		}
+		return nil
+	})
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
 	c.JSON(200, t)
@@ -411,40 +481,103 @@ func (h *Handler) UpdateTarget(c *gin.Context) {
 
 // DeleteTarget godoc
 // @Summary Delete a target from a mission
+// @Description Completed targets and the targets of completed, aborted or failed missions can't be deleted. Deleting the last open target of an active mission completes it (unless auto-completion is disabled).
 // @Tags missions
 // @Produce json
 // @Param id path int true "Mission ID"
 // @Param tid path int true "Target ID"
 // @Success 204 "No Content"
-// @Failure 400 {object} map[string]any
-// @Failure 404 {object} map[string]any
-// @Failure 500 {object} map[string]any
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "MISSION_COMPLETED, MISSION_CLOSED, TARGET_COMPLETED"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /missions/{id}/targets/{tid} [delete]
 func (h *Handler) DeleteTarget(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		c.JSON(400, gin.H{"error": "invalid id"})
+	id, ok := pathID(c, "id")
+	if !ok {
 		return
 	}
-
-	tid, _ := strconv.Atoi(c.Param("tid"))
-	var t models.Target
-	if err := h.db.First(&t, tid).Error; err != nil {
-		c.JSON(404, gin.H{"error": "target not found"})
+	tid, ok := pathID(c, "tid")
+	if !ok {
 		return
 	}
-	if t.MissionID != uint(id) {
-		c.JSON(400, gin.H{"error": "target not in mission"})
+	if err := h.deleteTarget(c.Request.Context(), id, tid); err != nil {
+		problem.Error(c, err)
 		return
 	}
-	if t.Completed {
-		c.JSON(400, gin.H{"error": "cannot delete completed target"})
-		return
+	c.Status(204)
+}
+
+// loadMission fetches the mission named by the :id path parameter, writing
+// the problem and returning false when it is invalid or missing, or
+// belongs to another cat than the caller limited to its own missions.
+func (h *Handler) loadMission(c *gin.Context) (*models.Mission, bool) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return nil, false
 	}
-	if err := h.db.Delete(&t).Error; err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
-		return
+	m, err := h.store.Missions().Get(c.Request.Context(), id)
+	if err != nil {
+		problem.Error(c, missionErr(err))
+		return nil, false
 	}
-	c.Status(204)
+	if cat, own := auth.OwnCat(c.Request.Context()); own && (m.AssignedCatID == nil || *m.AssignedCatID != cat) {
+		problem.Write(c, auth.Forbidden("mission is not assigned to you"))
+		return nil, false
+	}
+	return m, true
+}
+
+// loadTarget fetches the :tid target and checks it belongs to missionID.
+func (h *Handler) loadTarget(c *gin.Context, missionID uint) (*models.Target, bool) {
+	tid, ok := pathID(c, "tid")
+	if !ok {
+		return nil, false
+	}
+	t, err := h.store.Targets().Get(c.Request.Context(), tid)
+	if err != nil {
+		problem.Error(c, targetErr(err))
+		return nil, false
+	}
+	if t.MissionID != missionID {
+		problem.Write(c, problem.New(http.StatusNotFound, problem.CodeTargetNotInMission, "target not in mission"))
+		return nil, false
+	}
+	return t, true
+}
+
+// transition applies the lifecycle move to m and persists it with its
+// history record through s.
+func transition(ctx context.Context, s repository.Store, m *models.Mission, to models.MissionState, reason string) error {
+	tr, err := m.Transition(to, reason, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := s.Missions().Update(ctx, m); err != nil {
+		return err
+	}
+	return s.Missions().AddTransition(ctx, &tr)
+}
+
+// closedProblem explains why a terminal mission can't be changed.
+func closedProblem(m *models.Mission) *problem.Problem {
+	return problem.From(&models.ClosedError{State: m.State})
+}
+
+func missionErr(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.New(http.StatusNotFound, problem.CodeMissionNotFound, "mission not found")
+	}
+	return err
+}
+
+func targetErr(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.New(http.StatusNotFound, problem.CodeTargetNotFound, "target not found")
+	}
+	return err
 }
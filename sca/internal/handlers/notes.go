@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"sca/sca/internal/models"
+	"sca/sca/internal/notes"
+	"sca/sca/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListNoteRevisions godoc
+// @Summary List a target's notes revisions
+// @Description Every value the notes have had, oldest first. Revisions are numbered from 1 and never change; notes that were never set have none.
+// @Tags missions
+// @Produce json
+// @Param id path int true "Mission ID"
+// @Param tid path int true "Target ID"
+// @Success 200 {array} models.TargetNoteRevision
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem "MISSION_NOT_FOUND, TARGET_NOT_FOUND, TARGET_NOT_IN_MISSION"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id}/targets/{tid}/notes/history [get]
+func (h *Handler) ListNoteRevisions(c *gin.Context) {
+	m, ok := h.loadMission(c)
+	if !ok {
+		return
+	}
+	t, ok := h.loadTarget(c, m.ID)
+	if !ok {
+		return
+	}
+	list, err := h.store.Targets().NoteRevisions(c.Request.Context(), t.ID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(200, list)
+}
+
+type notesDiff struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	// Lines is the whole text with every line marked equal, insert or
+	// delete.
+	Lines []notes.Line `json:"lines"`
+	// Unified is the same diff in `diff -u` format, empty when the
+	// revisions are equal.
+	Unified string `json:"unified"`
+}
+
+// DiffNotes godoc
+// @Summary Compare two revisions of a target's notes
+// @Description Line diff from revision `from` to revision `to`. `to` defaults to the latest revision and `from` to the one before it; revision 0 stands for the empty notes before the first revision.
+// @Tags missions
+// @Produce json
+// @Param id path int true "Mission ID"
+// @Param tid path int true "Target ID"
+// @Param from query int false "Old revision (default: the one before to)"
+// @Param to query int false "New revision (default: the latest)"
+// @Success 200 {object} notesDiff
+// @Failure 400 {object} problem.Problem
+// @Failure 401 {object} problem.Problem "UNAUTHENTICATED"
+// @Failure 403 {object} problem.Problem "FORBIDDEN"
+// @Failure 404 {object} problem.Problem "MISSION_NOT_FOUND, TARGET_NOT_FOUND, TARGET_NOT_IN_MISSION, REVISION_NOT_FOUND"
+// @Failure 500 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /missions/{id}/targets/{tid}/notes/diff [get]
+func (h *Handler) DiffNotes(c *gin.Context) {
+	m, ok := h.loadMission(c)
+	if !ok {
+		return
+	}
+	t, ok := h.loadTarget(c, m.ID)
+	if !ok {
+		return
+	}
+	q := queryParser{c: c}
+	from, to := q.Int("from"), q.Int("to")
+	switch {
+	case q.err != nil:
+		problem.Error(c, q.err)
+		return
+	case from != nil && *from < 0, to != nil && *to < 0:
+		problem.Write(c, problem.New(http.StatusBadRequest, problem.CodeInvalidQuery, "revisions are numbered from 0"))
+		return
+	}
+
+	list, err := h.store.Targets().NoteRevisions(c.Request.Context(), t.ID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if to == nil {
+		latest := len(list)
+		to = &latest
+	}
+	if from == nil {
+		prev := max(*to-1, 0)
+		from = &prev
+	}
+	old, ok := revisionNotes(list, *from)
+	if !ok {
+		problem.Write(c, revisionNotFound(*from))
+		return
+	}
+	cur, ok := revisionNotes(list, *to)
+	if !ok {
+		problem.Write(c, revisionNotFound(*to))
+		return
+	}
+	diff := notes.Diff(old, cur)
+	c.JSON(200, notesDiff{
+		From:    *from,
+		To:      *to,
+		Lines:   diff,
+		Unified: notes.Unified(fmt.Sprintf("revision %d", *from), fmt.Sprintf("revision %d", *to), diff),
+	})
+}
+
+// revisionNotes returns the notes of revision n in list, where 0 is the
+// empty notes before the first revision.
+func revisionNotes(list []models.TargetNoteRevision, n int) (string, bool) {
+	if n == 0 {
+		return "", true
+	}
+	for _, r := range list {
+		if r.Revision == n {
+			return r.Notes, true
+		}
+	}
+	return "", false
+}
+
+func revisionNotFound(n int) *problem.Problem {
+	return problem.Newf(http.StatusNotFound, problem.CodeRevisionNotFound, "notes revision %d not found", n)
+}
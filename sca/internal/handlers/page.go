@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"sca/sca/internal/problem"
+	"sca/sca/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageParams reads limit, cursor and sort from the query string.
+func pageParams(c *gin.Context) (repository.Page, error) {
+	p := repository.Page{Cursor: c.Query("cursor"), Sort: c.Query("sort")}
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > repository.MaxPageLimit {
+			return p, problem.Newf(400, problem.CodeInvalidQuery, "limit must be between 1 and %d", repository.MaxPageLimit)
+		}
+		p.Limit = n
+	}
+	return p, nil
+}
+
+// setNextLink advertises the next page as an RFC 8288 Link header built
+// from the current request URL with the cursor replaced.
+func setNextLink(c *gin.Context, next string) {
+	if next == "" {
+		return
+	}
+	u := *c.Request.URL
+	q := u.Query()
+	q.Set("cursor", next)
+	u.RawQuery = q.Encode()
+	c.Header("Link", fmt.Sprintf("<%s>; rel=\"next\"", u.RequestURI()))
+}
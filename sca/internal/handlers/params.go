@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"sca/sca/internal/models"
+	"sca/sca/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pathID parses a positive integer path parameter, writing an INVALID_ID
+// problem and returning false when it is malformed.
+func pathID(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		problem.Write(c, problem.Newf(400, problem.CodeInvalidID, "invalid %s", name))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// bind decodes the JSON body into req and validates it, writing the
+// problem and returning false on failure.
+func (h *Handler) bind(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		problem.Error(c, err)
+		return false
+	}
+	if err := h.v.Struct(req); err != nil {
+		problem.Error(c, err)
+		return false
+	}
+	return true
+}
+
+// queryParser reads optional typed query parameters, keeping the first
+// parse error so a handler can check once after reading all of them.
+type queryParser struct {
+	c   *gin.Context
+	err error
+}
+
+func (q *queryParser) fail(format string, args ...any) {
+	if q.err == nil {
+		q.err = problem.Newf(400, problem.CodeInvalidQuery, format, args...)
+	}
+}
+
+func (q *queryParser) Int(key string) *int {
+	s := q.c.Query(key)
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		q.fail("%s must be an integer", key)
+		return nil
+	}
+	return &n
+}
+
+func (q *queryParser) Int64(key string) *int64 {
+	s := q.c.Query(key)
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		q.fail("%s must be an integer", key)
+		return nil
+	}
+	return &n
+}
+
+func (q *queryParser) Uint(key string) *uint {
+	s := q.c.Query(key)
+	if s == "" {
+		return nil
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n == 0 {
+		q.fail("%s must be a positive integer", key)
+		return nil
+	}
+	u := uint(n)
+	return &u
+}
+
+func (q *queryParser) States(key string) []models.MissionState {
+	s := q.c.Query(key)
+	if s == "" {
+		return nil
+	}
+	states, err := models.ParseMissionStates(s)
+	if err != nil {
+		q.fail("%s: %v", key, err)
+		return nil
+	}
+	return states
+}
+
+func (q *queryParser) Time(key string) *time.Time {
+	s := q.c.Query(key)
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		q.fail("%s must be an RFC 3339 timestamp", key)
+		return nil
+	}
+	return &t
+}
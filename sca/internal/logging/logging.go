@@ -0,0 +1,59 @@
+// Package logging builds the process-wide slog logger and carries
+// request-scoped loggers, tagged with the request ID, through
+// context.Context so every log line of one request can be correlated.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New returns a logger writing format ("json" or "text") records at level
+// and above to w.
+func New(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(format) {
+	case FormatJSON, "":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+type loggerKey struct{}
+type requestIDKey struct{}
+
+// NewContext returns a copy of ctx carrying l.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// From returns the logger carried by ctx, or slog.Default.
+func From(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID and a logger
+// derived from base that tags every record with it.
+func WithRequestID(ctx context.Context, base *slog.Logger, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	return NewContext(ctx, base.With("request_id", id))
+}
+
+// RequestID returns the request ID carried by ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces redacted values.
+const Redacted = "[REDACTED]"
+
+// Redactor hides the values at configured JSON paths. A path is a
+// dot-separated list of object keys (matched case-insensitively) where "*"
+// matches any single key or array element and "**" any number of levels:
+// "**.notes" hides notes at any depth, "items.*.salary_cents" only in the
+// items of a list response.
+type Redactor struct {
+	paths [][]string
+}
+
+func NewRedactor(paths []string) *Redactor {
+	r := &Redactor{}
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			r.paths = append(r.paths, strings.Split(p, "."))
+		}
+	}
+	return r
+}
+
+// RedactJSON returns body with every matching value replaced by Redacted.
+// ok is false when body is not valid JSON.
+func (r *Redactor) RedactJSON(body []byte) (out []byte, ok bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	out, err := json.Marshal(r.walk(v, nil))
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func (r *Redactor) walk(v any, path []string) any {
+	if len(path) > 0 && r.matches(path) {
+		return Redacted
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			t[k] = r.walk(child, append(path, k))
+		}
+	case []any:
+		for i, child := range t {
+			t[i] = r.walk(child, append(path, strconv.Itoa(i)))
+		}
+	}
+	return v
+}
+
+func (r *Redactor) matches(path []string) bool {
+	for _, p := range r.paths {
+		if match(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func match(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if match(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	if pattern[0] != "*" && !strings.EqualFold(pattern[0], path[0]) {
+		return false
+	}
+	return match(pattern[1:], path[1:])
+}
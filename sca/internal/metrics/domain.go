@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"sca/sca/internal/models"
+	"sca/sca/internal/repository"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statsTimeout bounds the queries run on each scrape.
+const statsTimeout = 5 * time.Second
+
+var (
+	missionsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "missions"),
+		"Missions by state.", []string{"state"}, nil)
+	idleCatsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "cats_idle"),
+		"Cats without an assigned or active mission.", nil, nil)
+	openTargetsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "targets_open"),
+		"Targets not yet completed on missions that are not closed.", nil, nil)
+)
+
+// domainCollector reads repository.Stats on every scrape, so the gauges
+// are always current and cost nothing between scrapes.
+type domainCollector struct {
+	stats repository.StatsReader
+}
+
+// RegisterDomain exposes the mission, cat and target gauges computed by
+// stats.
+func RegisterDomain(stats repository.StatsReader) error {
+	return Registry.Register(domainCollector{stats})
+}
+
+func (c domainCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- missionsDesc
+	ch <- idleCatsDesc
+	ch <- openTargetsDesc
+}
+
+func (c domainCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+	st, err := c.stats.Stats(ctx)
+	if err != nil {
+		// Leave the gauges out rather than fail the whole scrape.
+		slog.Warn("collecting domain metrics", "err", err)
+		return
+	}
+	for _, s := range models.MissionStates {
+		ch <- prometheus.MustNewConstMetric(missionsDesc, prometheus.GaugeValue, float64(st.MissionsByState[s]), string(s))
+	}
+	ch <- prometheus.MustNewConstMetric(idleCatsDesc, prometheus.GaugeValue, float64(st.IdleCats))
+	ch <- prometheus.MustNewConstMetric(openTargetsDesc, prometheus.GaugeValue, float64(st.OpenTargets))
+}
@@ -0,0 +1,111 @@
+// Package metrics defines the Prometheus metrics of the service and the
+// registry /metrics serves. Other packages record into the collectors
+// declared here so every metric name lives in one place.
+package metrics
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "sca"
+
+// Registry holds every metric of the process, including the Go runtime
+// and process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests being served.",
+	})
+
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of SQL statements issued through GORM by operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	DBQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "SQL statements issued through GORM that failed, by operation and table.",
+	}, []string{"operation", "table"})
+
+	CatAPIRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "thecatapi_requests_total",
+		Help:      "Requests to TheCatAPI by outcome (ok or error), plus calls failed by the open circuit breaker (circuit_open).",
+	}, []string{"outcome"})
+
+	CatAPIDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "thecatapi_request_duration_seconds",
+		Help:      "Latency of calls to TheCatAPI.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	catAPICacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "thecatapi_cache_lookups_total",
+		Help:      "Breed cache lookups by result (hit, stale or miss).",
+	}, []string{"result"})
+
+	cacheHits, cacheLookups atomic.Int64
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPDuration, HTTPInFlight,
+		DBQueryDuration, DBQueryErrors,
+		CatAPIRequests, CatAPIDuration, catAPICacheLookups,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "thecatapi_cache_hit_ratio",
+			Help:      "Share of breed cache lookups served from the cache since start.",
+		}, func() float64 {
+			n := cacheLookups.Load()
+			if n == 0 {
+				return 0
+			}
+			return float64(cacheHits.Load()) / float64(n)
+		}),
+	)
+}
+
+// Breed cache lookup results.
+const (
+	CacheHit = "hit"
+	// CacheStale is an expired list served because refreshing it failed.
+	CacheStale = "stale"
+	CacheMiss  = "miss"
+)
+
+// CatAPICacheLookup records one breed cache lookup. Hits and stale lookups
+// both count as served from the cache.
+func CatAPICacheLookup(result string) {
+	cacheLookups.Add(1)
+	if result != CacheMiss {
+		cacheHits.Add(1)
+	}
+	catAPICacheLookups.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Role is a set of permissions granted to a caller, see auth.Can.
+type Role string
+
+const (
+	// RoleHandler manages cats, missions and targets.
+	RoleHandler Role = "handler"
+	// RoleCat is a spy cat: it sees its own missions and updates the
+	// targets of its active one.
+	RoleCat Role = "cat"
+	// RoleFinance reads cats and alone may change their salary.
+	RoleFinance Role = "finance"
+)
+
+// Roles lists every role.
+var Roles = []Role{RoleHandler, RoleCat, RoleFinance}
+
+// Valid reports whether r is one of Roles.
+func (r Role) Valid() bool {
+	for _, v := range Roles {
+		if r == v {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey is a static credential. The secret itself is never stored, only
+// its hash; Prefix is the public part used to look the key up.
+type APIKey struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	Name   string `json:"name"`
+	Prefix string `json:"prefix"`
+	Hash   string `json:"-"`
+	Role   Role   `json:"role"`
+	// CatID is the cat a RoleCat key acts for.
+	CatID      *uint      `json:"cat_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// MissionAssignment records a cat being put on, taken off or swapped on a
+// mission. FromCatID is nil for the first assignment and ToCatID is nil
+// when the cat was removed.
+type MissionAssignment struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	MissionID uint      `json:"mission_id"`
+	FromCatID *uint     `json:"from_cat_id"`
+	ToCatID   *uint     `json:"to_cat_id"`
+	Actor     string    `json:"actor"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditEvent records one mutation: who made it, from where, and how the
+// entity's fields changed.
+type AuditEvent struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+	// Actor is the authenticated principal, empty when authentication is
+	// disabled.
+	Actor string `json:"actor"`
+	// Action is what happened to the entity: create, update, delete,
+	// transition, assign, unassign or reassign.
+	Action     string       `json:"action"`
+	EntityType string       `json:"entity_type"`
+	EntityID   uint         `json:"entity_id"`
+	Changes    AuditChanges `json:"changes" gorm:"type:jsonb"`
+	RequestID  string       `json:"request_id"`
+	IP         string       `json:"ip"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// AuditChanges maps each changed field to its values before and after the
+// mutation.
+type AuditChanges map[string]FieldChange
+
+// FieldChange holds a field's JSON value before and after a change. Before
+// is absent for created entities and After for deleted ones.
+type FieldChange struct {
+	Before json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After  json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+}
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(c)
+	return string(b), err
+}
+
+func (c *AuditChanges) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil:
+		*c = nil
+		return nil
+	}
+	return fmt.Errorf("scanning %T into AuditChanges", src)
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Breed sources: where a breed row was last synced from.
+const (
+	BreedSourceTheCatAPI = "thecatapi"
+	BreedSourceSeed      = "seed"
+	BreedSourceFile      = "file"
+)
+
+// Breed is an entry of the local breed catalog.
+type Breed struct {
+	ID   string `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+	// AltNames lists other names of the breed, comma-separated.
+	AltNames    string `json:"alt_names"`
+	Origin      string `json:"origin"`
+	CountryCode string `json:"country_code"`
+	Description string `json:"description"`
+	// Temperament lists the breed's traits, comma-separated, e.g.
+	// "Active, Energetic, Independent".
+	Temperament string `json:"temperament"`
+	// LifeSpan is a range in years, e.g. "12 - 15".
+	LifeSpan     string      `json:"life_span"`
+	Weight       BreedWeight `json:"weight" gorm:"embedded;embeddedPrefix:weight_"`
+	Traits       BreedTraits `json:"traits" gorm:"type:jsonb"`
+	WikipediaURL string      `json:"wikipedia_url"`
+	// Source is where the row was last synced from: thecatapi, seed or
+	// file.
+	Source    string    `json:"source"`
+	SyncedAt  time.Time `json:"synced_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BreedWeight is a weight range in pounds (Imperial) and kilograms
+// (Metric).
+type BreedWeight struct {
+	Imperial string `json:"imperial"`
+	Metric   string `json:"metric"`
+}
+
+// BreedTraits are TheCatAPI's ratings of a breed. Scores run from 1 to 5
+// and are 0 where unknown.
+type BreedTraits struct {
+	Adaptability     int `json:"adaptability"`
+	AffectionLevel   int `json:"affection_level"`
+	ChildFriendly    int `json:"child_friendly"`
+	DogFriendly      int `json:"dog_friendly"`
+	EnergyLevel      int `json:"energy_level"`
+	Grooming         int `json:"grooming"`
+	HealthIssues     int `json:"health_issues"`
+	Intelligence     int `json:"intelligence"`
+	SheddingLevel    int `json:"shedding_level"`
+	SocialNeeds      int `json:"social_needs"`
+	StrangerFriendly int `json:"stranger_friendly"`
+	Vocalisation     int `json:"vocalisation"`
+
+	Indoor         bool `json:"indoor"`
+	Lap            bool `json:"lap"`
+	Experimental   bool `json:"experimental"`
+	Hairless       bool `json:"hairless"`
+	Natural        bool `json:"natural"`
+	Rare           bool `json:"rare"`
+	Rex            bool `json:"rex"`
+	SuppressedTail bool `json:"suppressed_tail"`
+	ShortLegs      bool `json:"short_legs"`
+	Hypoallergenic bool `json:"hypoallergenic"`
+}
+
+// Value stores unknown traits as {} so that an upsert can tell them from
+// known ones.
+func (t BreedTraits) Value() (driver.Value, error) {
+	if t == (BreedTraits{}) {
+		return "{}", nil
+	}
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+func (t *BreedTraits) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	case nil:
+		*t = BreedTraits{}
+		return nil
+	}
+	return fmt.Errorf("scanning %T into BreedTraits", src)
+}
+
+// Summary returns what a cat shows of its breed.
+func (b Breed) Summary() *BreedSummary {
+	return &BreedSummary{Name: b.Name, Origin: b.Origin}
+}
+
+// BreedSummary is the part of a breed embedded in a cat.
+type BreedSummary struct {
+	Name   string `json:"name"`
+	Origin string `json:"origin"`
+}
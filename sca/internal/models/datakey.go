@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// MissionDataKey is the key that encrypts a mission's target notes, stored
+// wrapped by the master key MasterKeyID (see package encryption).
+type MissionDataKey struct {
+	ID          uint
+	MissionID   uint
+	MasterKeyID string
+	WrappedKey  []byte
+	CreatedAt   time.Time
+}
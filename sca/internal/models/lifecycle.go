@@ -0,0 +1,130 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MissionState is a step of the mission lifecycle:
+//
+//	draft → assigned → active → completed | aborted | failed
+//
+// draft and assigned missions may also be aborted, and an assigned mission
+// returns to draft when its cat is removed. The allowed moves live in
+// missionTransitions and nowhere else.
+type MissionState string
+
+const (
+	StateDraft     MissionState = "draft"
+	StateAssigned  MissionState = "assigned"
+	StateActive    MissionState = "active"
+	StateCompleted MissionState = "completed"
+	StateAborted   MissionState = "aborted"
+	StateFailed    MissionState = "failed"
+)
+
+// MissionStates lists every state in lifecycle order.
+var MissionStates = []MissionState{StateDraft, StateAssigned, StateActive, StateCompleted, StateAborted, StateFailed}
+
+var missionTransitions = map[MissionState][]MissionState{
+	StateDraft:    {StateAssigned, StateAborted},
+	StateAssigned: {StateActive, StateAborted, StateDraft},
+	StateActive:   {StateCompleted, StateAborted, StateFailed},
+}
+
+var (
+	ErrInvalidTransition = errors.New("invalid mission state transition")
+	ErrCatRequired       = errors.New("mission has no assigned cat")
+	ErrTargetsOpen       = errors.New("mission has open targets")
+)
+
+// ClosedError reports an attempt to change a mission in a terminal state.
+type ClosedError struct {
+	State MissionState
+}
+
+func (e *ClosedError) Error() string { return "mission " + string(e.State) }
+
+func (s MissionState) Valid() bool {
+	for _, v := range MissionStates {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Terminal reports whether the mission is closed for good.
+func (s MissionState) Terminal() bool {
+	return s == StateCompleted || s == StateAborted || s == StateFailed
+}
+
+// HoldsCat reports whether a mission in this state counts as the assigned
+// cat's one active mission (see ux_active_mission_per_cat).
+func (s MissionState) HoldsCat() bool {
+	return s == StateAssigned || s == StateActive
+}
+
+func (s MissionState) CanTransitionTo(to MissionState) bool {
+	for _, v := range missionTransitions[s] {
+		if v == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMissionStates parses a comma-separated list such as "assigned,active".
+func ParseMissionStates(raw string) ([]MissionState, error) {
+	var out []MissionState
+	for _, p := range strings.Split(raw, ",") {
+		s := MissionState(strings.TrimSpace(p))
+		if !s.Valid() {
+			return nil, fmt.Errorf("unknown mission state %q", p)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// MissionTransition records one state change of a mission.
+type MissionTransition struct {
+	ID        uint         `json:"id" gorm:"primaryKey"`
+	MissionID uint         `json:"mission_id"`
+	FromState MissionState `json:"from_state"`
+	ToState   MissionState `json:"to_state"`
+	Reason    string       `json:"reason"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Transition moves m to state to, returning the record to persist. It fails
+// with ErrInvalidTransition for moves the lifecycle doesn't allow and with
+// ErrCatRequired when entering a state that needs an assigned cat.
+func (m *Mission) Transition(to MissionState, reason string, at time.Time) (MissionTransition, error) {
+	if !m.State.CanTransitionTo(to) {
+		return MissionTransition{}, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, m.State, to)
+	}
+	if to.HoldsCat() && m.AssignedCatID == nil {
+		return MissionTransition{}, ErrCatRequired
+	}
+	if to == StateDraft && m.AssignedCatID != nil {
+		return MissionTransition{}, fmt.Errorf("%w: unassign the cat to return the mission to draft", ErrInvalidTransition)
+	}
+	tr := MissionTransition{MissionID: m.ID, FromState: m.State, ToState: to, Reason: reason, CreatedAt: at}
+	m.State = to
+	m.StateChangedAt = at
+	return tr, nil
+}
+
+// OpenTargets counts the targets that are not completed yet.
+func (m *Mission) OpenTargets() int {
+	n := 0
+	for _, t := range m.Targets {
+		if !t.Completed {
+			n++
+		}
+	}
+	return n
+}
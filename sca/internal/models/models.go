@@ -3,22 +3,30 @@ package models
 import "time"
 
 type Cat struct {
-	ID                uint      `json:"id" gorm:"primaryKey"`
-	Name              string    `json:"name" validate:"required,min=2"`
-	YearsOfExperience int       `json:"years_of_experience" validate:"gte=0"`
-	Breed             string    `json:"breed" validate:"required"`
-	SalaryCents       int64     `json:"salary_cents" validate:"gte=0"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID                uint   `json:"id" gorm:"primaryKey"`
+	Name              string `json:"name" validate:"required,min=2"`
+	YearsOfExperience int    `json:"years_of_experience" validate:"gte=0"`
+	// Breed is the ID of the cat's breed in the catalog.
+	Breed string `json:"breed" validate:"required"`
+	// BreedInfo is filled in when the cat is read back.
+	BreedInfo   *BreedSummary `json:"breed_info,omitempty" gorm:"-"`
+	SalaryCents int64         `json:"salary_cents" validate:"gte=0"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
 }
 
 type Mission struct {
-	ID            uint      `json:"id" gorm:"primaryKey"`
-	AssignedCatID *uint     `json:"assigned_cat_id"`
-	Completed     bool      `json:"completed"`
-	Targets       []Target  `json:"targets" gorm:"constraint:OnDelete:CASCADE"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID             uint         `json:"id" gorm:"primaryKey"`
+	AssignedCatID  *uint        `json:"assigned_cat_id"`
+	State          MissionState `json:"state"`
+	StateChangedAt time.Time    `json:"state_changed_at"`
+	// CompletionForced is set when the mission was completed with open
+	// targets; CompletionReason then says why.
+	CompletionForced bool      `json:"completion_forced"`
+	CompletionReason string    `json:"completion_reason,omitempty"`
+	Targets          []Target  `json:"targets" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
 
 type Target struct {
@@ -31,8 +39,3 @@ type Target struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
-
-type Breed struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// TargetNoteRevision is one value a target's notes have had. Revisions are
+// numbered from 1 per target and never change once written.
+type TargetNoteRevision struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TargetID  uint      `json:"target_id"`
+	Revision  int       `json:"revision"`
+	Author    string    `json:"author"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+}
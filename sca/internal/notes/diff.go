@@ -0,0 +1,231 @@
+package notes
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Op says what happened to a line between two revisions.
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+// Line is one line of a diff.
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// contextLines is the number of unchanged lines Unified keeps around a change.
+const contextLines = 3
+
+// Diff returns the shortest edit script turning a into b, line by line,
+// using Myers' algorithm. A trailing newline is not a line of its own.
+//
+// It runs in linear space: instead of keeping every step's furthest-reaching
+// paths to walk back through, it searches from both ends at once, splits at
+// the point where the searches meet and diffs the two halves.
+func Diff(a, b string) []Line {
+	x, y := lines(a), lines(b)
+	d := differ{out: []Line{}}
+	d.diff(x, y)
+	return d.out
+}
+
+type differ struct {
+	out []Line
+	// vf and vb hold the furthest-reaching paths of the forward and the
+	// backward search, reused across splits.
+	vf, vb []int
+}
+
+func (d *differ) emit(op Op, lines []string) {
+	for _, l := range lines {
+		d.out = append(d.out, Line{op, l})
+	}
+}
+
+func (d *differ) diff(x, y []string) {
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	d.emit(OpEqual, x[:pre])
+	x, y = x[pre:], y[pre:]
+	suf := 0
+	for suf < len(x) && suf < len(y) && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+	tail := x[len(x)-suf:]
+	x, y = x[:len(x)-suf], y[:len(y)-suf]
+
+	switch {
+	case len(x) == 0:
+		d.emit(OpInsert, y)
+	case len(y) == 0:
+		d.emit(OpDelete, x)
+	default:
+		i, j := d.split(x, y)
+		d.diff(x[:i], y[:j])
+		d.diff(x[i:], y[j:])
+	}
+	d.emit(OpEqual, tail)
+}
+
+// split finds a point (i, j) on a shortest edit path from x to y, which
+// differ in their first and in their last lines. The forward search runs
+// on diagonals k = i-j from (0, 0), the backward one on diagonals counted
+// from (n, m); they meet after about half the edits.
+func (d *differ) split(x, y []string) (int, int) {
+	n, m := len(x), len(y)
+	maxD := (n + m + 1) / 2
+	off := maxD + 1
+	size := 2*maxD + 3
+	if cap(d.vf) < size {
+		d.vf, d.vb = make([]int, size), make([]int, size)
+	}
+	vf, vb := d.vf[:size], d.vb[:size]
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[off+1], vb[off+1] = 0, 0
+	delta := n - m
+	// With an odd delta the paths meet during a forward step, otherwise
+	// during a backward one.
+	odd := delta%2 != 0
+	// The searches skip diagonals whose paths already ran off an edge.
+	fLo, fHi, bLo, bHi := 0, 0, 0, 0
+	for e := 0; e < maxD; e++ {
+		for k := -e + fLo; k <= e-fHi; k += 2 {
+			var i int
+			if k == -e || (k != e && vf[off+k-1] < vf[off+k+1]) {
+				i = vf[off+k+1]
+			} else {
+				i = vf[off+k-1] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i, j = i+1, j+1
+			}
+			vf[off+k] = i
+			switch {
+			case i > n:
+				fHi += 2
+			case j > m:
+				fLo += 2
+			case odd:
+				// The backward search has only done e-1 steps so far.
+				if kb := delta - k; kb > -e && kb < e && vb[off+kb] != -1 && i >= n-vb[off+kb] {
+					return i, j
+				}
+			}
+		}
+		for k := -e + bLo; k <= e-bHi; k += 2 {
+			var i int
+			if k == -e || (k != e && vb[off+k-1] < vb[off+k+1]) {
+				i = vb[off+k+1]
+			} else {
+				i = vb[off+k-1] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[n-1-i] == y[m-1-j] {
+				i, j = i+1, j+1
+			}
+			vb[off+k] = i
+			switch {
+			case i > n:
+				bHi += 2
+			case j > m:
+				bLo += 2
+			case !odd:
+				if kf := delta - k; kf >= -e && kf <= e && vf[off+kf] != -1 && vf[off+kf] >= n-i {
+					fi := vf[off+kf]
+					return fi, fi - kf
+				}
+			}
+		}
+	}
+	// Nothing in common: delete all of x, then insert all of y.
+	return n, 0
+}
+
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Unified formats diff like `diff -u`, labelling the sides from and to. It
+// is empty when nothing changed.
+func Unified(from, to string, diff []Line) string {
+	if !slices.ContainsFunc(diff, func(l Line) bool { return l.Op != OpEqual }) {
+		return ""
+	}
+	// before[i] and after[i] count the lines of each side ahead of diff[i].
+	before, after := make([]int, len(diff)+1), make([]int, len(diff)+1)
+	for i, l := range diff {
+		before[i+1], after[i+1] = before[i], after[i]
+		if l.Op != OpInsert {
+			before[i+1]++
+		}
+		if l.Op != OpDelete {
+			after[i+1]++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+	for i := 0; i < len(diff); {
+		for i < len(diff) && diff[i].Op == OpEqual {
+			i++
+		}
+		if i == len(diff) {
+			break
+		}
+		start, end := max(i-contextLines, 0), i
+		for {
+			for end < len(diff) && diff[end].Op != OpEqual {
+				end++
+			}
+			next := end
+			for next < len(diff) && diff[next].Op == OpEqual {
+				next++
+			}
+			if next == len(diff) || next-end > 2*contextLines {
+				end = min(end+contextLines, len(diff))
+				break
+			}
+			end = next
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(before[start], before[end]), hunkRange(after[start], after[end]))
+		for _, l := range diff[start:end] {
+			switch l.Op {
+			case OpEqual:
+				b.WriteByte(' ')
+			case OpInsert:
+				b.WriteByte('+')
+			case OpDelete:
+				b.WriteByte('-')
+			}
+			b.WriteString(l.Text)
+			b.WriteByte('\n')
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange formats the lines (from, to] of one side of a hunk; an empty
+// range names the line it follows.
+func hunkRange(from, to int) string {
+	if to == from {
+		return fmt.Sprintf("%d,0", from)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
@@ -0,0 +1,92 @@
+package notes
+
+import (
+	"math/rand"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	got := Diff("a\nb\nc\n", "a\nx\nc\nd\n")
+	want := []Line{{OpEqual, "a"}, {OpDelete, "b"}, {OpInsert, "x"}, {OpEqual, "c"}, {OpInsert, "d"}}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := Diff("", ""); got == nil || len(got) != 0 {
+		t.Fatalf("Diff of empty notes = %#v, want an empty list", got)
+	}
+}
+
+// TestDiffIsShortest compares Diff on random texts with the edit distance
+// from a longest common subsequence.
+func TestDiffIsShortest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	text := func() []string {
+		l := make([]string, rng.Intn(12))
+		for i := range l {
+			l[i] = string(rune('a' + rng.Intn(3)))
+		}
+		return l
+	}
+	for range 5000 {
+		x, y := text(), text()
+		diff := Diff(strings.Join(x, "\n"), strings.Join(y, "\n"))
+
+		var a, b []string
+		edits := 0
+		for _, l := range diff {
+			if l.Op != OpInsert {
+				a = append(a, l.Text)
+			}
+			if l.Op != OpDelete {
+				b = append(b, l.Text)
+			}
+			if l.Op != OpEqual {
+				edits++
+			}
+		}
+		if !slices.Equal(a, x) || !slices.Equal(b, y) {
+			t.Fatalf("Diff(%q, %q) = %v does not rebuild both sides", x, y, diff)
+		}
+		if want := len(x) + len(y) - 2*lcs(x, y); edits != want {
+			t.Fatalf("Diff(%q, %q) = %v has %d edits, want %d", x, y, diff, edits, want)
+		}
+	}
+}
+
+func lcs(x, y []string) int {
+	prev, cur := make([]int, len(y)+1), make([]int, len(y)+1)
+	for i := range x {
+		for j := range y {
+			if x[i] == y[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(y)]
+}
+
+// TestDiffLinearSpace diffs two long notes without a line in common, the
+// worst case for memory.
+func TestDiffLinearSpace(t *testing.T) {
+	var a, b strings.Builder
+	for i := range 5000 {
+		a.WriteString("old line " + string(rune('a'+i%26)) + "\n")
+		b.WriteString("new line " + string(rune('a'+i%26)) + "\n")
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	diff := Diff(a.String(), b.String())
+	runtime.ReadMemStats(&after)
+	if len(diff) != 10000 {
+		t.Fatalf("%d lines, want 10000", len(diff))
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 16<<20 {
+		t.Fatalf("allocated %d MiB", alloc>>20)
+	}
+}
@@ -0,0 +1,25 @@
+// Package notes keeps the revision history of target notes. Every change
+// is stored as a new revision through the Store of the transaction that
+// makes it, and any two revisions can be compared line by line.
+package notes
+
+import (
+	"context"
+
+	"sca/sca/internal/models"
+	"sca/sca/internal/repository"
+)
+
+// Record stores t's notes as a new revision by author through s unless they
+// equal prev, the notes t had before the change. New targets pass "" as
+// prev, so they only start a history when created with notes.
+func Record(ctx context.Context, s repository.Store, t *models.Target, prev, author string) error {
+	if t.Notes == prev {
+		return nil
+	}
+	return s.Targets().AddNoteRevision(ctx, &models.TargetNoteRevision{
+		TargetID: t.ID,
+		Author:   author,
+		Notes:    t.Notes,
+	})
+}
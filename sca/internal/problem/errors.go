@@ -0,0 +1,155 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"sca/sca/internal/assignment"
+	"sca/sca/internal/models"
+	"sca/sca/internal/repository"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// From maps err onto a Problem. Problems pass through unchanged, validation
+// and JSON decoding errors become 400s, repository errors (including the
+// Postgres constraint violations the repository translates) get their
+// specific code, and anything else is an opaque 500.
+func From(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		return Validation(verrs)
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return New(http.StatusBadRequest, CodeMalformedBody, "request body is not valid JSON")
+	case errors.As(err, &typeErr):
+		return Newf(http.StatusBadRequest, CodeMalformedBody, "field %q must be of type %s", typeErr.Field, typeErr.Type)
+	}
+
+	var busy *repository.CatBusyError
+	var closed *models.ClosedError
+	switch {
+	case errors.As(err, &busy):
+		p := Newf(http.StatusConflict, CodeCatAlreadyActive, "cat already assigned to active mission %d", busy.MissionID)
+		p.ConflictingMissionID = busy.MissionID
+		return p
+	case errors.As(err, &closed):
+		if closed.State == models.StateCompleted {
+			return New(http.StatusConflict, CodeMissionCompleted, "mission completed")
+		}
+		return Newf(http.StatusConflict, CodeMissionClosed, "mission %s", closed.State)
+	case errors.Is(err, assignment.ErrNotAssigned):
+		return New(http.StatusConflict, CodeMissionNotAssigned, "mission has no assigned cat")
+	case errors.Is(err, models.ErrInvalidTransition):
+		return New(http.StatusConflict, CodeInvalidTransition, err.Error())
+	case errors.Is(err, models.ErrCatRequired):
+		return New(http.StatusConflict, CodeCatRequired, "assign a cat before moving the mission to this state")
+	case errors.Is(err, models.ErrTargetsOpen):
+		return New(http.StatusConflict, CodeTargetsOpen, "mission has open targets; complete them or pass force=true with a reason")
+	case errors.Is(err, repository.ErrUnknownBreed):
+		return New(http.StatusBadRequest, CodeInvalidBreed, "breed not in the catalog")
+	case errors.Is(err, repository.ErrCatNotFound):
+		return New(http.StatusNotFound, CodeCatNotFound, "cat not found")
+	case errors.Is(err, repository.ErrCatBusy):
+		return New(http.StatusConflict, CodeCatAlreadyActive, "cat already assigned to an active mission")
+	case errors.Is(err, repository.ErrCatHasMissions):
+		return New(http.StatusConflict, CodeCatHasMissions, "cat is referenced by missions")
+	case errors.Is(err, repository.ErrNotEditable):
+		return New(http.StatusConflict, CodeMissionNotEditable, "mission is no longer a draft or already has a cat assigned")
+	case errors.Is(err, repository.ErrTooManyTargets):
+		return Newf(http.StatusConflict, CodeTooManyTargets, "a mission can have at most %d targets", repository.MaxTargetsPerMission)
+	case errors.Is(err, repository.ErrDuplicateTarget):
+		return New(http.StatusConflict, CodeDuplicateTarget, "target with this name already exists in mission")
+	case errors.Is(err, repository.ErrNotesFrozen):
+		return New(http.StatusConflict, CodeTargetNotesFrozen, "target completed; notes frozen")
+	case errors.Is(err, repository.ErrInvalidCursor):
+		return New(http.StatusBadRequest, CodeInvalidCursor, "cursor is malformed or was issued for a different sort")
+	case errors.Is(err, repository.ErrInvalidSort):
+		return New(http.StatusBadRequest, CodeInvalidSort, "unsupported sort field")
+	}
+	return New(http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+// Validation builds a 400 listing every failed rule by JSON field path.
+func Validation(verrs validator.ValidationErrors) *Problem {
+	p := New(http.StatusBadRequest, CodeValidation, "request validation failed")
+	for _, fe := range verrs {
+		p.Errors = append(p.Errors, FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: ruleMessage(fe),
+		})
+	}
+	return p
+}
+
+// fieldPath drops the struct name from the validator namespace, e.g.
+// "createMissionReq.targets[0].name" -> "targets[0].name". It relies on
+// the validator reporting JSON names (see JSONTagName).
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	var unit string
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if unit == " items" {
+			return "must contain at least " + fe.Param() + unit
+		}
+		return "must be at least " + fe.Param() + unit
+	case "max":
+		if unit == " items" {
+			return "must contain at most " + fe.Param() + unit
+		}
+		return "must be at most " + fe.Param() + unit
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "lte":
+		return "must be less than or equal to " + fe.Param()
+	case "required_with":
+		return "is required when " + strings.ToLower(fe.Param()) + " is set"
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	}
+	return fmt.Sprintf("failed the %q rule", fe.Tag())
+}
+
+// JSONTagName makes the validator report fields by their JSON names; pass it
+// to validator.Validate.RegisterTagNameFunc.
+func JSONTagName(f reflect.StructField) string {
+	name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
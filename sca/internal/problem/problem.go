@@ -0,0 +1,112 @@
+// Package problem renders API errors as RFC 7807 application/problem+json
+// documents carrying a stable machine-readable code.
+package problem
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"sca/sca/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+
+// Code identifies an error condition. Codes are part of the API contract:
+// clients switch on them, so existing values must never change meaning.
+type Code string
+
+const (
+	CodeMalformedBody      Code = "MALFORMED_BODY"
+	CodeValidation         Code = "VALIDATION_FAILED"
+	CodeInvalidID          Code = "INVALID_ID"
+	CodeInvalidQuery       Code = "INVALID_QUERY"
+	CodeInvalidCursor      Code = "INVALID_CURSOR"
+	CodeInvalidSort        Code = "INVALID_SORT"
+	CodeNoFields           Code = "NO_FIELDS"
+	CodeInvalidBreed       Code = "INVALID_BREED"
+	CodeRouteNotFound      Code = "ROUTE_NOT_FOUND"
+	CodeUnauthenticated    Code = "UNAUTHENTICATED"
+	CodeForbidden          Code = "FORBIDDEN"
+	CodeCatNotFound        Code = "CAT_NOT_FOUND"
+	CodeBreedNotFound      Code = "BREED_NOT_FOUND"
+	CodeMissionNotFound    Code = "MISSION_NOT_FOUND"
+	CodeTargetNotFound     Code = "TARGET_NOT_FOUND"
+	CodeTargetNotInMission Code = "TARGET_NOT_IN_MISSION"
+	CodeCatAlreadyActive   Code = "CAT_ALREADY_ACTIVE"
+	CodeCatHasMissions     Code = "CAT_HAS_MISSIONS"
+	CodeMissionCompleted   Code = "MISSION_COMPLETED"
+	CodeMissionClosed      Code = "MISSION_CLOSED"
+	CodeInvalidTransition  Code = "INVALID_TRANSITION"
+	CodeCatRequired        Code = "CAT_REQUIRED"
+	CodeTargetsOpen        Code = "TARGETS_OPEN"
+	CodeMissionAssigned    Code = "MISSION_ASSIGNED"
+	CodeMissionNotAssigned Code = "MISSION_NOT_ASSIGNED"
+	CodeMissionNotEditable Code = "MISSION_NOT_EDITABLE"
+	CodeTooManyTargets     Code = "TOO_MANY_TARGETS"
+	CodeDuplicateTarget    Code = "DUPLICATE_TARGET_NAME"
+	CodeTargetCompleted    Code = "TARGET_COMPLETED"
+	CodeTargetNotesFrozen  Code = "TARGET_NOTES_FROZEN"
+	CodeRevisionNotFound   Code = "REVISION_NOT_FOUND"
+	CodeInternal           Code = "INTERNAL"
+)
+
+// Problem is an RFC 7807 problem details object. Type is always
+// "about:blank", so Title is the HTTP status text and Code carries the
+// specific condition.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Code     Code         `json:"code"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	// ConflictingMissionID names the mission that holds the cat for
+	// CAT_ALREADY_ACTIVE problems, when known.
+	ConflictingMissionID uint `json:"conflicting_mission_id,omitempty"`
+}
+
+// FieldError describes one failed validation rule on a request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func New(status int, code Code, detail string) *Problem {
+	return &Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Code: code, Detail: detail}
+}
+
+func Newf(status int, code Code, format string, args ...any) *Problem {
+	return New(status, code, fmt.Sprintf(format, args...))
+}
+
+func (p *Problem) Error() string { return fmt.Sprintf("%s: %s", p.Code, p.Detail) }
+
+// Write sends p as the response and aborts the remaining handlers.
+func Write(c *gin.Context, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Abort()
+	c.Data(p.Status, ContentType, b)
+}
+
+// Error writes the problem for err, see From. Unexpected errors are logged
+// since the client only sees a generic INTERNAL problem.
+func Error(c *gin.Context, err error) {
+	p := From(err)
+	if p.Status >= http.StatusInternalServerError {
+		logging.From(c.Request.Context()).Error("request failed", "method", c.Request.Method, "path", c.Request.URL.Path, "err", err)
+	}
+	Write(c, p)
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"sca/sca/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	Record(ctx context.Context, e *models.AuditEvent) error
+	// List returns one page of events and the cursor of the next page.
+	List(ctx context.Context, f AuditFilter, p Page) ([]models.AuditEvent, string, error)
+}
+
+// AuditSortFields are the columns accepted in Page.Sort for audit events.
+var AuditSortFields = []string{"id", "created_at"}
+
+// AuditFilter narrows AuditRepository.List; zero values match everything.
+type AuditFilter struct {
+	EntityType  string
+	EntityID    *uint
+	Actor       string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+func auditSortKey(field string) func(models.AuditEvent) (any, uint) {
+	return func(e models.AuditEvent) (any, uint) {
+		if field == "created_at" {
+			return e.CreatedAt, e.ID
+		}
+		return int64(e.ID), e.ID
+	}
+}
+
+type pgAudit struct{ db *gorm.DB }
+
+func (r pgAudit) Record(ctx context.Context, e *models.AuditEvent) error {
+	return translate(r.db.WithContext(ctx).Create(e).Error)
+}
+
+func (r pgAudit) List(ctx context.Context, f AuditFilter, p Page) ([]models.AuditEvent, string, error) {
+	spec, err := parseSort(p.Sort, AuditSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	q := r.db.WithContext(ctx).Model(&models.AuditEvent{})
+	if f.EntityType != "" {
+		q = q.Where("audit_events.entity_type = ?", f.EntityType)
+	}
+	if f.EntityID != nil {
+		q = q.Where("audit_events.entity_id = ?", *f.EntityID)
+	}
+	if f.Actor != "" {
+		q = q.Where("audit_events.actor = ?", f.Actor)
+	}
+	if f.CreatedFrom != nil {
+		q = q.Where("audit_events.created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		q = q.Where("audit_events.created_at < ?", *f.CreatedTo)
+	}
+	if q, err = keyset(q, "audit_events", spec, p); err != nil {
+		return nil, "", err
+	}
+	var list []models.AuditEvent
+	if err := q.Find(&list).Error; err != nil {
+		return nil, "", translate(err)
+	}
+	list, next := trimPage(list, spec, p.limit(), auditSortKey(spec.field))
+	return list, next, nil
+}
+
+type memAudit struct{ memStore }
+
+func (r memAudit) Record(_ context.Context, e *models.AuditEvent) error {
+	return r.do(func(s *memState) error {
+		e.ID = s.nextID("audit_events")
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = time.Now()
+		}
+		s.audit[e.ID] = *e
+		return nil
+	})
+}
+
+func (r memAudit) List(_ context.Context, f AuditFilter, p Page) ([]models.AuditEvent, string, error) {
+	spec, err := parseSort(p.Sort, AuditSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	out := []models.AuditEvent{}
+	r.view(func(s *memState) error {
+		for _, e := range s.audit {
+			if f.matches(e) {
+				out = append(out, e)
+			}
+		}
+		return nil
+	})
+	return paginate(out, spec, p, auditSortKey(spec.field))
+}
+
+func (f AuditFilter) matches(e models.AuditEvent) bool {
+	switch {
+	case f.EntityType != "" && e.EntityType != f.EntityType,
+		f.EntityID != nil && e.EntityID != *f.EntityID,
+		f.Actor != "" && e.Actor != f.Actor,
+		f.CreatedFrom != nil && e.CreatedAt.Before(*f.CreatedFrom),
+		f.CreatedTo != nil && !e.CreatedAt.Before(*f.CreatedTo):
+		return false
+	}
+	return true
+}
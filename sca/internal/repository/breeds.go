@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"sca/sca/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BreedRepository interface {
+	// List returns the whole catalog ordered by name.
+	List(ctx context.Context) ([]models.Breed, error)
+	// Find returns the breed whose ID or name is nameOrID, ignoring case
+	// and surrounding spaces, or ErrNotFound.
+	Find(ctx context.Context, nameOrID string) (*models.Breed, error)
+	// Upsert inserts the breeds, replacing the rows with the same IDs.
+	// Details a breed leaves empty (all but its ID and name) keep their
+	// stored values.
+	Upsert(ctx context.Context, breeds []models.Breed) error
+	Stats(ctx context.Context) (BreedStats, error)
+}
+
+// BreedStats summarizes the catalog.
+type BreedStats struct {
+	Count int64
+	// LastSyncedAt and LastSource describe the most recently synced breed;
+	// both are zero for an empty catalog.
+	LastSyncedAt time.Time
+	LastSource   string
+}
+
+func breedKey(nameOrID string) string { return strings.ToLower(strings.TrimSpace(nameOrID)) }
+
+// breedDetailColumns keep their stored value when an upserted row leaves
+// them empty: the bundled list and file exports may carry only some of
+// them, and loading one must not erase what a full sync stored.
+var breedDetailColumns = []string{
+	"alt_names", "origin", "country_code", "description", "temperament", "life_span",
+	"weight_imperial", "weight_metric", "wikipedia_url",
+}
+
+// breedUpsertSet is what Upsert writes over an existing row; created_at is
+// kept.
+func breedUpsertSet() clause.Set {
+	set := clause.AssignmentColumns([]string{"name", "source", "synced_at", "updated_at"})
+	for _, col := range breedDetailColumns {
+		set = append(set, clause.Assignment{
+			Column: clause.Column{Name: col},
+			Value:  gorm.Expr(fmt.Sprintf("COALESCE(NULLIF(EXCLUDED.%s, ''), breeds.%s)", col, col)),
+		})
+	}
+	return append(set, clause.Assignment{
+		Column: clause.Column{Name: "traits"},
+		Value:  gorm.Expr("COALESCE(NULLIF(EXCLUDED.traits, '{}'::jsonb), breeds.traits)"),
+	})
+}
+
+type pgBreeds struct{ db *gorm.DB }
+
+func (r pgBreeds) List(ctx context.Context) ([]models.Breed, error) {
+	list := []models.Breed{}
+	if err := r.db.WithContext(ctx).Order("name, id").Find(&list).Error; err != nil {
+		return nil, translate(err)
+	}
+	return list, nil
+}
+
+func (r pgBreeds) Find(ctx context.Context, nameOrID string) (*models.Breed, error) {
+	key := breedKey(nameOrID)
+	var b models.Breed
+	if err := r.db.WithContext(ctx).Where("lower(id) = ? OR lower(name) = ?", key, key).Order("id").First(&b).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &b, nil
+}
+
+func (r pgBreeds) Upsert(ctx context.Context, breeds []models.Breed) error {
+	if len(breeds) == 0 {
+		return nil
+	}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: breedUpsertSet(),
+	}).Create(&breeds).Error
+	return translate(err)
+}
+
+func (r pgBreeds) Stats(ctx context.Context) (BreedStats, error) {
+	var st BreedStats
+	if err := r.db.WithContext(ctx).Model(&models.Breed{}).Count(&st.Count).Error; err != nil {
+		return st, translate(err)
+	}
+	var last models.Breed
+	if err := r.db.WithContext(ctx).Order("synced_at DESC, id").Limit(1).Find(&last).Error; err != nil {
+		return st, translate(err)
+	}
+	st.LastSyncedAt, st.LastSource = last.SyncedAt, last.Source
+	return st, nil
+}
+
+type memBreeds struct{ memStore }
+
+func (r memBreeds) List(_ context.Context) ([]models.Breed, error) {
+	list := []models.Breed{}
+	r.view(func(s *memState) error {
+		for _, b := range s.breeds {
+			list = append(list, b)
+		}
+		return nil
+	})
+	slices.SortFunc(list, func(a, b models.Breed) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+	return list, nil
+}
+
+func (r memBreeds) Find(_ context.Context, nameOrID string) (*models.Breed, error) {
+	key := breedKey(nameOrID)
+	var found *models.Breed
+	r.view(func(s *memState) error {
+		for _, b := range s.breeds {
+			if (strings.ToLower(b.ID) == key || strings.ToLower(b.Name) == key) && (found == nil || b.ID < found.ID) {
+				found = &b
+			}
+		}
+		return nil
+	})
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+func (r memBreeds) Upsert(_ context.Context, breeds []models.Breed) error {
+	return r.do(func(s *memState) error {
+		now := time.Now()
+		for i := range breeds {
+			b := &breeds[i]
+			b.CreatedAt, b.UpdatedAt = now, now
+			row := *b
+			if old, ok := s.breeds[b.ID]; ok {
+				b.CreatedAt = old.CreatedAt
+				row.CreatedAt = old.CreatedAt
+				keepDetails(&row, old)
+			}
+			s.breeds[b.ID] = row
+		}
+		return nil
+	})
+}
+
+func (r memBreeds) Stats(_ context.Context) (BreedStats, error) {
+	var st BreedStats
+	r.view(func(s *memState) error {
+		st.Count = int64(len(s.breeds))
+		for _, b := range s.breeds {
+			if b.SyncedAt.After(st.LastSyncedAt) {
+				st.LastSyncedAt, st.LastSource = b.SyncedAt, b.Source
+			}
+		}
+		return nil
+	})
+	return st, nil
+}
+
+// keepDetails fills the details b leaves empty from old, as breedUpsertSet
+// does.
+func keepDetails(b *models.Breed, old models.Breed) {
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&b.AltNames, old.AltNames},
+		{&b.Origin, old.Origin},
+		{&b.CountryCode, old.CountryCode},
+		{&b.Description, old.Description},
+		{&b.Temperament, old.Temperament},
+		{&b.LifeSpan, old.LifeSpan},
+		{&b.Weight.Imperial, old.Weight.Imperial},
+		{&b.Weight.Metric, old.Weight.Metric},
+		{&b.WikipediaURL, old.WikipediaURL},
+	} {
+		if *f.dst == "" {
+			*f.dst = f.src
+		}
+	}
+	if b.Traits == (models.BreedTraits{}) {
+		b.Traits = old.Traits
+	}
+}
+
+// attachBreeds sets BreedInfo on cats from the catalog.
+func attachBreeds(ctx context.Context, db *gorm.DB, cats []models.Cat) error {
+	if len(cats) == 0 {
+		return nil
+	}
+	ids := make([]string, len(cats))
+	for i, c := range cats {
+		ids[i] = c.Breed
+	}
+	var list []models.Breed
+	if err := db.WithContext(ctx).Select("id, name, origin").Where("id IN ?", ids).Find(&list).Error; err != nil {
+		return translate(err)
+	}
+	byID := make(map[string]models.Breed, len(list))
+	for _, b := range list {
+		byID[b.ID] = b
+	}
+	for i := range cats {
+		if b, ok := byID[cats[i].Breed]; ok {
+			cats[i].BreedInfo = b.Summary()
+		}
+	}
+	return nil
+}
+
+// withBreed is the cat with BreedInfo set from the catalog.
+func (s *memState) withBreed(c models.Cat) models.Cat {
+	if b, ok := s.breeds[c.Breed]; ok {
+		c.BreedInfo = b.Summary()
+	}
+	return c
+}
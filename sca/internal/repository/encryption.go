@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"sca/sca/internal/encryption"
+	"sca/sca/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoMasterKey is returned when reading sealed notes, or rotating keys,
+// without master keys configured.
+var ErrNoMasterKey = errors.New("target notes are encrypted but no master key is configured")
+
+// PostgresOption configures NewPostgres.
+type PostgresOption func(*Postgres)
+
+// WithNotesKeys makes the store encrypt target notes and their revisions
+// with per-mission data keys wrapped by keys. Without it notes are written
+// in plaintext. Plaintext notes stay readable either way.
+func WithNotesKeys(keys *encryption.Keyring) PostgresOption {
+	return func(p *Postgres) {
+		if keys != nil {
+			p.notes = &noteSealer{keys: keys}
+		}
+	}
+}
+
+// noteSealer encrypts and decrypts notes for the Postgres store. Data keys
+// never change once written, so unwrapped ones are cached by ID for the
+// life of the process.
+type noteSealer struct {
+	keys  *encryption.Keyring
+	cache sync.Map // data key ID -> []byte
+}
+
+// seal encrypts notes with the newest data key of the mission, creating
+// the mission's first one when needed. Empty notes, and all notes without
+// a sealer, are stored as they are.
+func (n *noteSealer) seal(ctx context.Context, db *gorm.DB, missionID uint, notes string) (string, error) {
+	if n == nil || notes == "" {
+		return notes, nil
+	}
+	var k models.MissionDataKey
+	err := db.WithContext(ctx).Where("mission_id = ?", missionID).Order("id DESC").Limit(1).Find(&k).Error
+	if err != nil {
+		return "", translate(err)
+	}
+	var dek []byte
+	if k.ID == 0 {
+		if k, dek, err = n.newDataKey(ctx, db, missionID); err != nil {
+			return "", err
+		}
+	} else if dek, err = n.unwrap(k); err != nil {
+		return "", err
+	}
+	return encryption.Seal(k.ID, dek, notes)
+}
+
+// open returns the plaintext of stored notes.
+func (n *noteSealer) open(ctx context.Context, db *gorm.DB, stored string) (string, error) {
+	id, ok := encryption.DataKeyID(stored)
+	if !ok {
+		return stored, nil
+	}
+	if n == nil {
+		return "", ErrNoMasterKey
+	}
+	dek, err := n.dataKey(ctx, db, id)
+	if err != nil {
+		return "", err
+	}
+	return encryption.Open(dek, stored)
+}
+
+func (n *noteSealer) openTargets(ctx context.Context, db *gorm.DB, targets []models.Target) error {
+	for i := range targets {
+		var err error
+		if targets[i].Notes, err = n.open(ctx, db, targets[i].Notes); err != nil {
+			return fmt.Errorf("target %d: %w", targets[i].ID, err)
+		}
+	}
+	return nil
+}
+
+func (n *noteSealer) dataKey(ctx context.Context, db *gorm.DB, id uint) ([]byte, error) {
+	if dek, ok := n.cache.Load(id); ok {
+		return dek.([]byte), nil
+	}
+	var k models.MissionDataKey
+	if err := db.WithContext(ctx).First(&k, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("data key %d not found", id)
+		}
+		return nil, translate(err)
+	}
+	return n.unwrap(k)
+}
+
+func (n *noteSealer) unwrap(k models.MissionDataKey) ([]byte, error) {
+	dek, err := n.keys.Unwrap(k.MasterKeyID, k.WrappedKey, missionAAD(k.MissionID))
+	if err != nil {
+		return nil, fmt.Errorf("data key %d: %w", k.ID, err)
+	}
+	n.cache.Store(k.ID, dek)
+	return dek, nil
+}
+
+func (n *noteSealer) newDataKey(ctx context.Context, db *gorm.DB, missionID uint) (models.MissionDataKey, []byte, error) {
+	dek, err := encryption.NewDataKey()
+	if err != nil {
+		return models.MissionDataKey{}, nil, err
+	}
+	k := models.MissionDataKey{MissionID: missionID}
+	if k.MasterKeyID, k.WrappedKey, err = n.keys.Wrap(dek, missionAAD(missionID)); err != nil {
+		return k, nil, err
+	}
+	if err := db.WithContext(ctx).Create(&k).Error; err != nil {
+		return k, nil, translate(err)
+	}
+	n.cache.Store(k.ID, dek)
+	return k, dek, nil
+}
+
+// missionAAD binds a wrapped data key to its mission, so a key row copied
+// to another mission doesn't unwrap.
+func missionAAD(missionID uint) []byte {
+	return []byte("mission:" + strconv.FormatUint(uint64(missionID), 10))
+}
+
+// RekeyMode selects what Rekey does to each mission's notes.
+type RekeyMode int
+
+const (
+	// RekeyPlaintext encrypts notes still stored in plaintext and leaves
+	// sealed ones alone.
+	RekeyPlaintext RekeyMode = iota
+	// RekeyRotate re-encrypts all notes under a new data key wrapped by
+	// the current master key and drops the old data keys.
+	RekeyRotate
+	// RekeyDecrypt stores all notes in plaintext again and drops the data
+	// keys.
+	RekeyDecrypt
+)
+
+// Rekey applies mode to the missions after afterID, at most limit of them,
+// in one transaction that locks them like every notes change does. It
+// returns the last mission it looked at, 0 when there were none left, and
+// how many notes and revisions it rewrote. Callers loop until it returns 0.
+func (p *Postgres) Rekey(ctx context.Context, mode RekeyMode, afterID uint, limit int) (last uint, rewritten int, err error) {
+	if p.notes == nil && mode != RekeyDecrypt {
+		return 0, 0, ErrNoMasterKey
+	}
+	err = p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET LOCAL sca.rekeying = 'on'").Error; err != nil {
+			return err
+		}
+		var ids []uint
+		err := tx.Model(&models.Mission{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id > ?", afterID).Order("id").Limit(limit).Pluck("id", &ids).Error
+		if err != nil {
+			return translate(err)
+		}
+		for _, id := range ids {
+			n, err := p.rekeyMission(ctx, tx, mode, id)
+			if err != nil {
+				return fmt.Errorf("mission %d: %w", id, err)
+			}
+			rewritten += n
+			last = id
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return last, rewritten, nil
+}
+
+// storedNotes is a notes value as it is in the database, in targets or in
+// target_note_revisions.
+type storedNotes struct {
+	ID    uint
+	Notes string
+}
+
+func (p *Postgres) rekeyMission(ctx context.Context, tx *gorm.DB, mode RekeyMode, missionID uint) (int, error) {
+	var targets, revisions []storedNotes
+	err := tx.Model(&models.Target{}).Select("id, notes").Where("mission_id = ? AND notes <> ''", missionID).Find(&targets).Error
+	if err != nil {
+		return 0, translate(err)
+	}
+	err = tx.Model(&models.TargetNoteRevision{}).Select("id, notes").
+		Where("notes <> '' AND target_id IN (SELECT id FROM targets WHERE mission_id = ?)", missionID).Find(&revisions).Error
+	if err != nil {
+		return 0, translate(err)
+	}
+
+	var (
+		keep models.MissionDataKey
+		dek  []byte
+	)
+	rewrite := func(table string, rows []storedNotes) (int, error) {
+		n := 0
+		for _, row := range rows {
+			if mode == RekeyPlaintext && encryption.IsSealed(row.Notes) {
+				continue
+			}
+			plain, err := p.notes.open(ctx, tx, row.Notes)
+			if err != nil {
+				return n, err
+			}
+			out := plain
+			switch mode {
+			case RekeyPlaintext:
+				if out, err = p.notes.seal(ctx, tx, missionID, plain); err != nil {
+					return n, err
+				}
+			case RekeyRotate:
+				if dek == nil {
+					if keep, dek, err = p.notes.newDataKey(ctx, tx, missionID); err != nil {
+						return n, err
+					}
+				}
+				if out, err = encryption.Seal(keep.ID, dek, plain); err != nil {
+					return n, err
+				}
+			}
+			if err := tx.Table(table).Where("id = ?", row.ID).UpdateColumn("notes", out).Error; err != nil {
+				return n, translate(err)
+			}
+			n++
+		}
+		return n, nil
+	}
+	nt, err := rewrite("targets", targets)
+	if err != nil {
+		return 0, err
+	}
+	nr, err := rewrite("target_note_revisions", revisions)
+	if err != nil {
+		return 0, err
+	}
+	if mode != RekeyPlaintext {
+		if err := tx.Where("mission_id = ? AND id <> ?", missionID, keep.ID).Delete(&models.MissionDataKey{}).Error; err != nil {
+			return 0, translate(err)
+		}
+	}
+	return nt + nr, nil
+}
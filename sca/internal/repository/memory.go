@@ -0,0 +1,723 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sca/sca/internal/models"
+)
+
+// Memory is an in-process Store for tests and local experiments. It enforces
+// the invariants the Postgres schema does: one active mission per cat, at
+// most MaxTargetsPerMission targets, unique target names per mission and
+// frozen notes on completed targets.
+type Memory struct {
+	mu sync.Mutex
+	s  *memState
+}
+
+func NewMemory() *Memory { return &Memory{s: newMemState()} }
+
+func (m *Memory) Cats() CatRepository         { return memCats{memStore{m: m}} }
+func (m *Memory) Missions() MissionRepository { return memMissions{memStore{m: m}} }
+func (m *Memory) Targets() TargetRepository   { return memTargets{memStore{m: m}} }
+func (m *Memory) APIKeys() APIKeyRepository   { return memAPIKeys{memStore{m: m}} }
+func (m *Memory) Audit() AuditRepository      { return memAudit{memStore{m: m}} }
+func (m *Memory) Breeds() BreedRepository     { return memBreeds{memStore{m: m}} }
+
+func (m *Memory) Tx(ctx context.Context, fn func(s Store) error) error {
+	return memStore{m: m}.Tx(ctx, fn)
+}
+
+type memState struct {
+	seq      map[string]uint // per-table ID sequences, like BIGSERIAL
+	cats     map[uint]models.Cat
+	missions map[uint]models.Mission
+	targets  map[uint]models.Target
+	history  map[uint]models.MissionTransition
+	handover map[uint]models.MissionAssignment
+	notes    map[uint]models.TargetNoteRevision
+	apiKeys  map[uint]models.APIKey
+	audit    map[uint]models.AuditEvent
+	breeds   map[string]models.Breed
+}
+
+func newMemState() *memState {
+	return &memState{
+		seq:      map[string]uint{},
+		cats:     map[uint]models.Cat{},
+		missions: map[uint]models.Mission{},
+		targets:  map[uint]models.Target{},
+		history:  map[uint]models.MissionTransition{},
+		handover: map[uint]models.MissionAssignment{},
+		notes:    map[uint]models.TargetNoteRevision{},
+		apiKeys:  map[uint]models.APIKey{},
+		audit:    map[uint]models.AuditEvent{},
+		breeds:   map[string]models.Breed{},
+	}
+}
+
+func (s *memState) clone() *memState {
+	c := newMemState()
+	for k, v := range s.seq {
+		c.seq[k] = v
+	}
+	for k, v := range s.cats {
+		c.cats[k] = v
+	}
+	for k, v := range s.missions {
+		c.missions[k] = v
+	}
+	for k, v := range s.targets {
+		c.targets[k] = v
+	}
+	for k, v := range s.history {
+		c.history[k] = v
+	}
+	for k, v := range s.handover {
+		c.handover[k] = v
+	}
+	for k, v := range s.notes {
+		c.notes[k] = v
+	}
+	for k, v := range s.apiKeys {
+		c.apiKeys[k] = v
+	}
+	for k, v := range s.audit {
+		c.audit[k] = v
+	}
+	for k, v := range s.breeds {
+		c.breeds[k] = v
+	}
+	return c
+}
+
+func (s *memState) nextID(table string) uint {
+	s.seq[table]++
+	return s.seq[table]
+}
+
+// mission returns the stored mission with its targets attached.
+func (s *memState) mission(id uint) (models.Mission, bool) {
+	m, ok := s.missions[id]
+	if !ok {
+		return m, false
+	}
+	m.Targets = s.missionTargets(id)
+	return m, true
+}
+
+func (s *memState) missionTargets(missionID uint) []models.Target {
+	out := []models.Target{}
+	for _, t := range s.targets {
+		if t.MissionID == missionID {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// checkActive enforces ux_active_mission_per_cat for mission m.
+func (s *memState) checkActive(m models.Mission) error {
+	if m.AssignedCatID == nil {
+		return nil
+	}
+	if _, ok := s.cats[*m.AssignedCatID]; !ok {
+		return ErrCatNotFound
+	}
+	if !m.State.HoldsCat() {
+		return nil
+	}
+	for id, other := range s.missions {
+		if id != m.ID && other.State.HoldsCat() && other.AssignedCatID != nil && *other.AssignedCatID == *m.AssignedCatID {
+			return &CatBusyError{CatID: *m.AssignedCatID, MissionID: id}
+		}
+	}
+	return nil
+}
+
+// deleteTarget removes the target with its notes history, like the
+// target_note_revisions foreign key does.
+func (s *memState) deleteTarget(id uint) {
+	delete(s.targets, id)
+	for rid, rev := range s.notes {
+		if rev.TargetID == id {
+			delete(s.notes, rid)
+		}
+	}
+}
+
+// checkTargets enforces the max-targets trigger and ux_targets_mission_name.
+func (s *memState) checkTargets(missionID uint, add []models.Target) error {
+	existing := s.missionTargets(missionID)
+	if len(existing)+len(add) > MaxTargetsPerMission {
+		return ErrTooManyTargets
+	}
+	names := map[string]struct{}{}
+	for _, t := range existing {
+		names[t.Name] = struct{}{}
+	}
+	for _, t := range add {
+		if _, ok := names[t.Name]; ok {
+			return ErrDuplicateTarget
+		}
+		names[t.Name] = struct{}{}
+	}
+	return nil
+}
+
+// memStore is a view of Memory; inTx views run under the lock already held
+// by Tx and must not take it again.
+type memStore struct {
+	m    *Memory
+	inTx bool
+}
+
+func (v memStore) Cats() CatRepository         { return memCats{v} }
+func (v memStore) Missions() MissionRepository { return memMissions{v} }
+func (v memStore) Targets() TargetRepository   { return memTargets{v} }
+func (v memStore) APIKeys() APIKeyRepository   { return memAPIKeys{v} }
+func (v memStore) Audit() AuditRepository      { return memAudit{v} }
+func (v memStore) Breeds() BreedRepository     { return memBreeds{v} }
+
+func (v memStore) Tx(_ context.Context, fn func(s Store) error) error {
+	if v.inTx {
+		return fn(v)
+	}
+	v.m.mu.Lock()
+	defer v.m.mu.Unlock()
+	snapshot := v.m.s.clone()
+	if err := fn(memStore{m: v.m, inTx: true}); err != nil {
+		v.m.s = snapshot
+		return err
+	}
+	return nil
+}
+
+// do runs fn against the current state, rolling back whatever fn changed
+// if it fails so single operations are atomic as well.
+func (v memStore) do(fn func(s *memState) error) error {
+	if v.inTx {
+		return fn(v.m.s)
+	}
+	v.m.mu.Lock()
+	defer v.m.mu.Unlock()
+	snapshot := v.m.s.clone()
+	if err := fn(v.m.s); err != nil {
+		v.m.s = snapshot
+		return err
+	}
+	return nil
+}
+
+// view runs a read-only fn against the current state.
+func (v memStore) view(fn func(s *memState) error) error {
+	if v.inTx {
+		return fn(v.m.s)
+	}
+	v.m.mu.Lock()
+	defer v.m.mu.Unlock()
+	return fn(v.m.s)
+}
+
+func (f CatFilter) matches(c models.Cat) bool {
+	switch {
+	case f.Breed != "" && c.Breed != f.Breed,
+		f.MinExperience != nil && c.YearsOfExperience < *f.MinExperience,
+		f.MaxExperience != nil && c.YearsOfExperience > *f.MaxExperience,
+		f.MinSalary != nil && c.SalaryCents < *f.MinSalary,
+		f.MaxSalary != nil && c.SalaryCents > *f.MaxSalary:
+		return false
+	}
+	return true
+}
+
+func (f MissionFilter) matches(m models.Mission) bool {
+	switch {
+	case len(f.States) > 0 && !slices.Contains(f.States, m.State),
+		f.AssignedCatID != nil && (m.AssignedCatID == nil || *m.AssignedCatID != *f.AssignedCatID),
+		f.CreatedFrom != nil && m.CreatedAt.Before(*f.CreatedFrom),
+		f.CreatedTo != nil && !m.CreatedAt.Before(*f.CreatedTo):
+		return false
+	}
+	if f.TargetCountry == "" {
+		return true
+	}
+	for _, t := range m.Targets {
+		if strings.EqualFold(t.Country, f.TargetCountry) {
+			return true
+		}
+	}
+	return false
+}
+
+type memCats struct{ memStore }
+
+func (r memCats) Create(_ context.Context, cat *models.Cat) error {
+	return r.do(func(s *memState) error {
+		if _, ok := s.breeds[cat.Breed]; !ok {
+			return ErrUnknownBreed
+		}
+		now := time.Now()
+		cat.ID = s.nextID("cats")
+		cat.CreatedAt, cat.UpdatedAt = now, now
+		s.cats[cat.ID] = *cat
+		return nil
+	})
+}
+
+func (r memCats) Get(_ context.Context, id uint) (*models.Cat, error) {
+	var cat models.Cat
+	err := r.view(func(s *memState) error {
+		c, ok := s.cats[id]
+		if !ok {
+			return ErrNotFound
+		}
+		cat = s.withBreed(c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cat, nil
+}
+
+// GetForUpdate is Get: transactions already run under the store's lock.
+func (r memCats) GetForUpdate(ctx context.Context, id uint) (*models.Cat, error) {
+	return r.Get(ctx, id)
+}
+
+func (r memCats) List(_ context.Context, f CatFilter, p Page) ([]models.Cat, string, error) {
+	spec, err := parseSort(p.Sort, CatSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	out := []models.Cat{}
+	r.view(func(s *memState) error {
+		for _, c := range s.cats {
+			if f.matches(c) {
+				out = append(out, s.withBreed(c))
+			}
+		}
+		return nil
+	})
+	return paginate(out, spec, p, catSortKey(spec.field))
+}
+
+func (r memCats) Update(_ context.Context, id uint, updates map[string]any) (*models.Cat, error) {
+	var cat models.Cat
+	err := r.do(func(s *memState) error {
+		c, ok := s.cats[id]
+		if !ok {
+			return ErrNotFound
+		}
+		for k, v := range updates {
+			switch k {
+			case "salary_cents":
+				c.SalaryCents = v.(int64)
+			case "name":
+				c.Name = v.(string)
+			case "years_of_experience":
+				c.YearsOfExperience = v.(int)
+			case "breed":
+				if _, ok := s.breeds[v.(string)]; !ok {
+					return ErrUnknownBreed
+				}
+				c.Breed = v.(string)
+			}
+		}
+		c.UpdatedAt = time.Now()
+		s.cats[id] = c
+		cat = s.withBreed(c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cat, nil
+}
+
+func (r memCats) Delete(_ context.Context, id uint) error {
+	return r.do(func(s *memState) error {
+		if _, ok := s.cats[id]; !ok {
+			return ErrNotFound
+		}
+		for _, m := range s.missions {
+			if m.AssignedCatID != nil && *m.AssignedCatID == id {
+				return ErrCatHasMissions
+			}
+		}
+		delete(s.cats, id)
+		for kid, k := range s.apiKeys {
+			if k.CatID != nil && *k.CatID == id {
+				delete(s.apiKeys, kid)
+			}
+		}
+		return nil
+	})
+}
+
+type memMissions struct{ memStore }
+
+func (r memMissions) Create(_ context.Context, m *models.Mission) error {
+	return r.do(func(s *memState) error {
+		now := time.Now()
+		m.ID = s.nextID("missions")
+		m.CreatedAt, m.UpdatedAt = now, now
+		if err := s.checkActive(*m); err != nil {
+			return err
+		}
+		if err := s.checkTargets(m.ID, m.Targets); err != nil {
+			return err
+		}
+		for i := range m.Targets {
+			t := &m.Targets[i]
+			t.ID = s.nextID("targets")
+			t.MissionID = m.ID
+			t.CreatedAt, t.UpdatedAt = now, now
+			s.targets[t.ID] = *t
+		}
+		stored := *m
+		stored.Targets = nil
+		s.missions[m.ID] = stored
+		return nil
+	})
+}
+
+func (r memMissions) Get(_ context.Context, id uint) (*models.Mission, error) {
+	var m models.Mission
+	err := r.view(func(s *memState) error {
+		var ok bool
+		if m, ok = s.mission(id); !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetForUpdate is Get: transactions already run under the store's lock.
+func (r memMissions) GetForUpdate(ctx context.Context, id uint) (*models.Mission, error) {
+	return r.Get(ctx, id)
+}
+
+func (r memMissions) List(_ context.Context, f MissionFilter, p Page) ([]models.Mission, string, error) {
+	spec, err := parseSort(p.Sort, MissionSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	out := []models.Mission{}
+	r.view(func(s *memState) error {
+		for id := range s.missions {
+			if m, _ := s.mission(id); f.matches(m) {
+				out = append(out, m)
+			}
+		}
+		return nil
+	})
+	return paginate(out, spec, p, missionSortKey(spec.field))
+}
+
+func (r memMissions) Update(_ context.Context, m *models.Mission) error {
+	return r.do(func(s *memState) error {
+		if _, ok := s.missions[m.ID]; !ok {
+			return ErrNotFound
+		}
+		if err := s.checkActive(*m); err != nil {
+			return err
+		}
+		m.UpdatedAt = time.Now()
+		stored := *m
+		stored.Targets = nil
+		s.missions[m.ID] = stored
+		return nil
+	})
+}
+
+func (r memMissions) Delete(_ context.Context, id uint) error {
+	return r.do(func(s *memState) error {
+		if _, ok := s.missions[id]; !ok {
+			return ErrNotFound
+		}
+		for tid, t := range s.targets {
+			if t.MissionID == id {
+				s.deleteTarget(tid)
+			}
+		}
+		for hid, t := range s.history {
+			if t.MissionID == id {
+				delete(s.history, hid)
+			}
+		}
+		for aid, a := range s.handover {
+			if a.MissionID == id {
+				delete(s.handover, aid)
+			}
+		}
+		delete(s.missions, id)
+		return nil
+	})
+}
+
+func (r memMissions) HeldBy(_ context.Context, catID uint) (*models.Mission, error) {
+	var m models.Mission
+	err := r.view(func(s *memState) error {
+		for _, other := range s.missions {
+			if other.State.HoldsCat() && other.AssignedCatID != nil && *other.AssignedCatID == catID {
+				m = other
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r memMissions) AddTransition(_ context.Context, t *models.MissionTransition) error {
+	return r.do(func(s *memState) error {
+		if _, ok := s.missions[t.MissionID]; !ok {
+			return ErrNotFound
+		}
+		t.ID = s.nextID("mission_transitions")
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = time.Now()
+		}
+		s.history[t.ID] = *t
+		return nil
+	})
+}
+
+func (r memMissions) Transitions(_ context.Context, missionID uint) ([]models.MissionTransition, error) {
+	out := []models.MissionTransition{}
+	err := r.view(func(s *memState) error {
+		for _, t := range s.history {
+			if t.MissionID == missionID {
+				out = append(out, t)
+			}
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, err
+}
+
+func (r memMissions) AddAssignment(_ context.Context, a *models.MissionAssignment) error {
+	return r.do(func(s *memState) error {
+		if _, ok := s.missions[a.MissionID]; !ok {
+			return ErrNotFound
+		}
+		a.ID = s.nextID("mission_assignments")
+		if a.CreatedAt.IsZero() {
+			a.CreatedAt = time.Now()
+		}
+		s.handover[a.ID] = *a
+		return nil
+	})
+}
+
+func (r memMissions) Assignments(_ context.Context, missionID uint) ([]models.MissionAssignment, error) {
+	out := []models.MissionAssignment{}
+	err := r.view(func(s *memState) error {
+		for _, a := range s.handover {
+			if a.MissionID == missionID {
+				out = append(out, a)
+			}
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, err
+}
+
+type memTargets struct{ memStore }
+
+func (r memTargets) Add(_ context.Context, missionID uint, targets []models.Target) ([]models.Target, error) {
+	err := r.do(func(s *memState) error {
+		if _, ok := s.missions[missionID]; !ok {
+			return ErrNotFound
+		}
+		if err := s.checkTargets(missionID, targets); err != nil {
+			return err
+		}
+		now := time.Now()
+		for i := range targets {
+			t := &targets[i]
+			t.ID = s.nextID("targets")
+			t.MissionID = missionID
+			t.CreatedAt, t.UpdatedAt = now, now
+			s.targets[t.ID] = *t
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+func (r memTargets) Get(_ context.Context, id uint) (*models.Target, error) {
+	var t models.Target
+	err := r.view(func(s *memState) error {
+		var ok bool
+		if t, ok = s.targets[id]; !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r memTargets) Update(_ context.Context, t *models.Target) error {
+	return r.do(func(s *memState) error {
+		old, ok := s.targets[t.ID]
+		if !ok {
+			return ErrNotFound
+		}
+		if old.Completed && old.Notes != t.Notes {
+			return ErrNotesFrozen
+		}
+		if old.Name != t.Name || old.MissionID != t.MissionID {
+			siblings := s.missionTargets(t.MissionID)
+			for _, o := range siblings {
+				if o.ID != t.ID && o.Name == t.Name {
+					return ErrDuplicateTarget
+				}
+			}
+		}
+		t.UpdatedAt = time.Now()
+		s.targets[t.ID] = *t
+		return nil
+	})
+}
+
+func (r memTargets) Delete(_ context.Context, id uint) error {
+	return r.do(func(s *memState) error {
+		if _, ok := s.targets[id]; !ok {
+			return ErrNotFound
+		}
+		s.deleteTarget(id)
+		return nil
+	})
+}
+
+func (r memTargets) AddNoteRevision(_ context.Context, rev *models.TargetNoteRevision) error {
+	return r.do(func(s *memState) error {
+		if _, ok := s.targets[rev.TargetID]; !ok {
+			return ErrNotFound
+		}
+		rev.Revision = 1
+		for _, other := range s.notes {
+			if other.TargetID == rev.TargetID && other.Revision >= rev.Revision {
+				rev.Revision = other.Revision + 1
+			}
+		}
+		rev.ID = s.nextID("target_note_revisions")
+		if rev.CreatedAt.IsZero() {
+			rev.CreatedAt = time.Now()
+		}
+		s.notes[rev.ID] = *rev
+		return nil
+	})
+}
+
+func (r memTargets) NoteRevisions(_ context.Context, targetID uint) ([]models.TargetNoteRevision, error) {
+	out := []models.TargetNoteRevision{}
+	err := r.view(func(s *memState) error {
+		for _, rev := range s.notes {
+			if rev.TargetID == targetID {
+				out = append(out, rev)
+			}
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Revision < out[j].Revision })
+	return out, err
+}
+
+type memAPIKeys struct{ memStore }
+
+func (r memAPIKeys) Create(_ context.Context, k *models.APIKey) error {
+	return r.do(func(s *memState) error {
+		for _, other := range s.apiKeys {
+			if other.Prefix == k.Prefix {
+				return fmt.Errorf("api key prefix %q already exists", k.Prefix)
+			}
+		}
+		if k.CatID != nil {
+			if _, ok := s.cats[*k.CatID]; !ok {
+				return ErrCatNotFound
+			}
+		}
+		k.ID = s.nextID("api_keys")
+		if k.CreatedAt.IsZero() {
+			k.CreatedAt = time.Now()
+		}
+		s.apiKeys[k.ID] = *k
+		return nil
+	})
+}
+
+func (r memAPIKeys) GetByPrefix(_ context.Context, prefix string) (*models.APIKey, error) {
+	var out *models.APIKey
+	err := r.view(func(s *memState) error {
+		for _, k := range s.apiKeys {
+			if k.Prefix == prefix {
+				out = &k
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	return out, err
+}
+
+func (r memAPIKeys) List(context.Context) ([]models.APIKey, error) {
+	out := []models.APIKey{}
+	err := r.view(func(s *memState) error {
+		for _, k := range s.apiKeys {
+			out = append(out, k)
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, err
+}
+
+func (r memAPIKeys) Revoke(_ context.Context, id uint, at time.Time) error {
+	return r.do(func(s *memState) error {
+		k, ok := s.apiKeys[id]
+		if !ok || k.RevokedAt != nil {
+			return ErrNotFound
+		}
+		k.RevokedAt = &at
+		s.apiKeys[id] = k
+		return nil
+	})
+}
+
+func (r memAPIKeys) Touch(_ context.Context, id uint, at time.Time) error {
+	return r.do(func(s *memState) error {
+		k, ok := s.apiKeys[id]
+		if !ok {
+			return ErrNotFound
+		}
+		k.LastUsedAt = &at
+		s.apiKeys[id] = k
+		return nil
+	})
+}
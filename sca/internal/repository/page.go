@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sca/sca/internal/models"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort")
+)
+
+// Page selects a slice of a keyset-paginated listing. Sort is a whitelisted
+// field name, prefixed with "-" for descending order; ties always break on
+// id in the same direction. Cursor is the NextCursor of the previous page.
+type Page struct {
+	Limit  int
+	Sort   string
+	Cursor string
+}
+
+type valueKind int
+
+const (
+	kindInt valueKind = iota
+	kindString
+	kindTime
+)
+
+// CatSortFields and MissionSortFields are the columns accepted in Page.Sort.
+var (
+	CatSortFields     = []string{"id", "name", "years_of_experience", "salary_cents", "created_at"}
+	MissionSortFields = []string{"id", "created_at", "updated_at"}
+)
+
+var sortKinds = map[string]valueKind{
+	"id":                  kindInt,
+	"name":                kindString,
+	"years_of_experience": kindInt,
+	"salary_cents":        kindInt,
+	"created_at":          kindTime,
+	"updated_at":          kindTime,
+}
+
+// sortSpec is a parsed, validated Page.Sort.
+type sortSpec struct {
+	field string
+	desc  bool
+	kind  valueKind
+}
+
+func (s sortSpec) String() string {
+	if s.desc {
+		return "-" + s.field
+	}
+	return s.field
+}
+
+func parseSort(raw string, allowed []string) (sortSpec, error) {
+	if raw == "" {
+		return sortSpec{field: "id", kind: kindInt}, nil
+	}
+	spec := sortSpec{field: strings.TrimPrefix(raw, "-"), desc: strings.HasPrefix(raw, "-")}
+	for _, f := range allowed {
+		if f == spec.field {
+			spec.kind = sortKinds[f]
+			return spec, nil
+		}
+	}
+	return sortSpec{}, ErrInvalidSort
+}
+
+func (p Page) limit() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultPageLimit
+	case p.Limit > MaxPageLimit:
+		return MaxPageLimit
+	}
+	return p.Limit
+}
+
+// cursor is the decoded form of an opaque page token: the sort it was issued
+// for plus the sort value and id of the last row already returned.
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"i"`
+}
+
+func encodeCursor(spec sortSpec, value any, id uint) string {
+	c := cursor{Sort: spec.String(), ID: id}
+	switch v := value.(type) {
+	case int64:
+		c.Value = strconv.FormatInt(v, 10)
+	case string:
+		c.Value = v
+	case time.Time:
+		c.Value = v.UTC().Format(time.RFC3339Nano)
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor returns the typed sort value and id stored in token. A token
+// issued for a different sort is rejected.
+func decodeCursor(token string, spec sortSpec) (any, uint, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != spec.String() {
+		return nil, 0, ErrInvalidCursor
+	}
+	switch spec.kind {
+	case kindInt:
+		v, err := strconv.ParseInt(c.Value, 10, 64)
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return v, c.ID, nil
+	case kindTime:
+		v, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return v, c.ID, nil
+	}
+	return c.Value, c.ID, nil
+}
+
+// compareValues orders two sort values of the same kind.
+func compareValues(a, b any) int {
+	switch x := a.(type) {
+	case int64:
+		y := b.(int64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(x, b.(string))
+	case time.Time:
+		return x.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+// CatFilter narrows CatRepository.List; zero values match everything.
+type CatFilter struct {
+	// Breed is a breed ID.
+	Breed         string
+	MinExperience *int
+	MaxExperience *int
+	MinSalary     *int64
+	MaxSalary     *int64
+}
+
+// MissionFilter narrows MissionRepository.List; zero values match everything.
+type MissionFilter struct {
+	States        []models.MissionState
+	AssignedCatID *uint
+	// TargetCountry matches missions with at least one target in the country.
+	TargetCountry string
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+}
+
+func catSortKey(field string) func(models.Cat) (any, uint) {
+	return func(c models.Cat) (any, uint) {
+		switch field {
+		case "name":
+			return c.Name, c.ID
+		case "years_of_experience":
+			return int64(c.YearsOfExperience), c.ID
+		case "salary_cents":
+			return c.SalaryCents, c.ID
+		case "created_at":
+			return c.CreatedAt, c.ID
+		}
+		return int64(c.ID), c.ID
+	}
+}
+
+func missionSortKey(field string) func(models.Mission) (any, uint) {
+	return func(m models.Mission) (any, uint) {
+		switch field {
+		case "created_at":
+			return m.CreatedAt, m.ID
+		case "updated_at":
+			return m.UpdatedAt, m.ID
+		}
+		return int64(m.ID), m.ID
+	}
+}
+
+// trimPage cuts rows, fetched with one extra row, down to the page limit and
+// returns the cursor for the following page ("" when this is the last one).
+func trimPage[T any](rows []T, spec sortSpec, limit int, key func(T) (any, uint)) ([]T, string) {
+	if len(rows) <= limit {
+		return rows, ""
+	}
+	rows = rows[:limit]
+	v, id := key(rows[limit-1])
+	return rows, encodeCursor(spec, v, id)
+}
+
+// paginate sorts, seeks past the cursor and trims rows in memory, producing
+// the same pages the Postgres keyset queries do.
+func paginate[T any](rows []T, spec sortSpec, p Page, key func(T) (any, uint)) ([]T, string, error) {
+	order := func(av any, aid uint, bv any, bid uint) int {
+		c := compareValues(av, bv)
+		if c == 0 {
+			c = compareValues(int64(aid), int64(bid))
+		}
+		if spec.desc {
+			c = -c
+		}
+		return c
+	}
+	slices.SortFunc(rows, func(a, b T) int {
+		av, aid := key(a)
+		bv, bid := key(b)
+		return order(av, aid, bv, bid)
+	})
+
+	if p.Cursor != "" {
+		cv, cid, err := decodeCursor(p.Cursor, spec)
+		if err != nil {
+			return nil, "", err
+		}
+		start := len(rows)
+		for i, r := range rows {
+			if v, id := key(r); order(v, id, cv, cid) > 0 {
+				start = i
+				break
+			}
+		}
+		rows = rows[start:]
+	}
+	limit := p.limit()
+	if len(rows) > limit+1 {
+		rows = rows[:limit+1]
+	}
+	out, next := trimPage(rows, spec, limit, key)
+	return out, next, nil
+}
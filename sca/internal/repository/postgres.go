@@ -0,0 +1,480 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"sca/sca/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Postgres is the GORM-backed Store.
+type Postgres struct {
+	db    *gorm.DB
+	notes *noteSealer
+}
+
+func NewPostgres(db *gorm.DB, opts ...PostgresOption) *Postgres {
+	p := &Postgres{db: db}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Postgres) Cats() CatRepository         { return pgCats{p.db} }
+func (p *Postgres) Missions() MissionRepository { return pgMissions{p.db, p.notes} }
+func (p *Postgres) Targets() TargetRepository   { return pgTargets{p.db, p.notes} }
+func (p *Postgres) APIKeys() APIKeyRepository   { return pgAPIKeys{p.db} }
+func (p *Postgres) Audit() AuditRepository      { return pgAudit{p.db} }
+func (p *Postgres) Breeds() BreedRepository     { return pgBreeds{p.db} }
+
+func (p *Postgres) Tx(ctx context.Context, fn func(s Store) error) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&Postgres{db: tx, notes: p.notes})
+	})
+}
+
+type pgCats struct{ db *gorm.DB }
+
+func (r pgCats) Create(ctx context.Context, cat *models.Cat) error {
+	return translate(r.db.WithContext(ctx).Create(cat).Error)
+}
+
+func (r pgCats) Get(ctx context.Context, id uint) (*models.Cat, error) {
+	return r.get(ctx, r.db, id)
+}
+
+func (r pgCats) GetForUpdate(ctx context.Context, id uint) (*models.Cat, error) {
+	return r.get(ctx, r.db.Clauses(clause.Locking{Strength: "UPDATE"}), id)
+}
+
+func (r pgCats) get(ctx context.Context, q *gorm.DB, id uint) (*models.Cat, error) {
+	cats := make([]models.Cat, 1)
+	if err := q.WithContext(ctx).First(&cats[0], id).Error; err != nil {
+		return nil, translate(err)
+	}
+	if err := attachBreeds(ctx, r.db, cats); err != nil {
+		return nil, err
+	}
+	return &cats[0], nil
+}
+
+func (r pgCats) List(ctx context.Context, f CatFilter, p Page) ([]models.Cat, string, error) {
+	spec, err := parseSort(p.Sort, CatSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	q := r.db.WithContext(ctx).Model(&models.Cat{})
+	if f.Breed != "" {
+		q = q.Where("cats.breed = ?", f.Breed)
+	}
+	if f.MinExperience != nil {
+		q = q.Where("cats.years_of_experience >= ?", *f.MinExperience)
+	}
+	if f.MaxExperience != nil {
+		q = q.Where("cats.years_of_experience <= ?", *f.MaxExperience)
+	}
+	if f.MinSalary != nil {
+		q = q.Where("cats.salary_cents >= ?", *f.MinSalary)
+	}
+	if f.MaxSalary != nil {
+		q = q.Where("cats.salary_cents <= ?", *f.MaxSalary)
+	}
+	if q, err = keyset(q, "cats", spec, p); err != nil {
+		return nil, "", err
+	}
+	var cats []models.Cat
+	if err := q.Find(&cats).Error; err != nil {
+		return nil, "", translate(err)
+	}
+	cats, next := trimPage(cats, spec, p.limit(), catSortKey(spec.field))
+	if err := attachBreeds(ctx, r.db, cats); err != nil {
+		return nil, "", err
+	}
+	return cats, next, nil
+}
+
+func (r pgCats) Update(ctx context.Context, id uint, updates map[string]any) (*models.Cat, error) {
+	res := r.db.WithContext(ctx).Model(&models.Cat{}).Where("id = ?", id).Updates(updates)
+	if res.Error != nil {
+		return nil, translate(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return r.Get(ctx, id)
+}
+
+func (r pgCats) Delete(ctx context.Context, id uint) error {
+	res := r.db.WithContext(ctx).Delete(&models.Cat{}, id)
+	if res.Error != nil {
+		return translate(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type pgMissions struct {
+	db    *gorm.DB
+	notes *noteSealer
+}
+
+// Create inserts the mission first and then its targets, whose notes are
+// sealed with the new mission's data key.
+func (r pgMissions) Create(ctx context.Context, m *models.Mission) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(m).Error; err != nil {
+			return translate(err)
+		}
+		if len(m.Targets) == 0 {
+			return nil
+		}
+		_, err := pgTargets{tx, r.notes}.Add(ctx, m.ID, m.Targets)
+		return err
+	})
+}
+
+func (r pgMissions) Get(ctx context.Context, id uint) (*models.Mission, error) {
+	var m models.Mission
+	if err := r.db.WithContext(ctx).Preload("Targets", orderByID).First(&m, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	if err := r.notes.openTargets(ctx, r.db, m.Targets); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r pgMissions) GetForUpdate(ctx context.Context, id uint) (*models.Mission, error) {
+	var m models.Mission
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Targets", orderByID).First(&m, id).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	if err := r.notes.openTargets(ctx, r.db, m.Targets); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r pgMissions) List(ctx context.Context, f MissionFilter, p Page) ([]models.Mission, string, error) {
+	spec, err := parseSort(p.Sort, MissionSortFields)
+	if err != nil {
+		return nil, "", err
+	}
+	q := r.db.WithContext(ctx).Model(&models.Mission{}).Preload("Targets", orderByID)
+	if len(f.States) > 0 {
+		q = q.Where("missions.state IN ?", f.States)
+	}
+	if f.AssignedCatID != nil {
+		q = q.Where("missions.assigned_cat_id = ?", *f.AssignedCatID)
+	}
+	if f.TargetCountry != "" {
+		q = q.Where("EXISTS (SELECT 1 FROM targets t WHERE t.mission_id = missions.id AND lower(t.country) = lower(?))", f.TargetCountry)
+	}
+	if f.CreatedFrom != nil {
+		q = q.Where("missions.created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		q = q.Where("missions.created_at < ?", *f.CreatedTo)
+	}
+	if q, err = keyset(q, "missions", spec, p); err != nil {
+		return nil, "", err
+	}
+	var list []models.Mission
+	if err := q.Find(&list).Error; err != nil {
+		return nil, "", translate(err)
+	}
+	list, next := trimPage(list, spec, p.limit(), missionSortKey(spec.field))
+	for i := range list {
+		if err := r.notes.openTargets(ctx, r.db, list[i].Targets); err != nil {
+			return nil, "", err
+		}
+	}
+	return list, next, nil
+}
+
+func (r pgMissions) Update(ctx context.Context, m *models.Mission) error {
+	return translate(r.db.WithContext(ctx).Omit(clause.Associations).Save(m).Error)
+}
+
+func (r pgMissions) Delete(ctx context.Context, id uint) error {
+	res := r.db.WithContext(ctx).Delete(&models.Mission{}, id)
+	if res.Error != nil {
+		return translate(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r pgMissions) HeldBy(ctx context.Context, catID uint) (*models.Mission, error) {
+	var m models.Mission
+	err := r.db.WithContext(ctx).
+		Where("assigned_cat_id = ? AND state IN ?", catID, []models.MissionState{models.StateAssigned, models.StateActive}).
+		First(&m).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &m, nil
+}
+
+func (r pgMissions) AddTransition(ctx context.Context, t *models.MissionTransition) error {
+	return translate(r.db.WithContext(ctx).Create(t).Error)
+}
+
+func (r pgMissions) Transitions(ctx context.Context, missionID uint) ([]models.MissionTransition, error) {
+	var list []models.MissionTransition
+	if err := r.db.WithContext(ctx).Where("mission_id = ?", missionID).Order("id").Find(&list).Error; err != nil {
+		return nil, translate(err)
+	}
+	return list, nil
+}
+
+func (r pgMissions) AddAssignment(ctx context.Context, a *models.MissionAssignment) error {
+	return translate(r.db.WithContext(ctx).Create(a).Error)
+}
+
+func (r pgMissions) Assignments(ctx context.Context, missionID uint) ([]models.MissionAssignment, error) {
+	var list []models.MissionAssignment
+	if err := r.db.WithContext(ctx).Where("mission_id = ?", missionID).Order("id").Find(&list).Error; err != nil {
+		return nil, translate(err)
+	}
+	return list, nil
+}
+
+type pgTargets struct {
+	db    *gorm.DB
+	notes *noteSealer
+}
+
+// Add inserts the targets with sealed notes and hands them back with IDs
+// and timestamps set and their notes in plaintext.
+func (r pgTargets) Add(ctx context.Context, missionID uint, targets []models.Target) ([]models.Target, error) {
+	rows := make([]models.Target, len(targets))
+	for i := range targets {
+		targets[i].MissionID = missionID
+		rows[i] = targets[i]
+		var err error
+		if rows[i].Notes, err = r.notes.seal(ctx, r.db, missionID, targets[i].Notes); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.db.WithContext(ctx).Create(&rows).Error; err != nil {
+		return nil, translate(err)
+	}
+	for i := range rows {
+		rows[i].Notes = targets[i].Notes
+	}
+	copy(targets, rows)
+	return targets, nil
+}
+
+func (r pgTargets) Get(ctx context.Context, id uint) (*models.Target, error) {
+	var t models.Target
+	if err := r.db.WithContext(ctx).First(&t, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	var err error
+	if t.Notes, err = r.notes.open(ctx, r.db, t.Notes); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Update saves t, sealing its notes afresh only when they changed. Every
+// seal uses a new nonce, so resealing the same notes would change the
+// stored value and trip tg_freeze_completed_notes on a completed target.
+func (r pgTargets) Update(ctx context.Context, t *models.Target) error {
+	var stored models.Target
+	if err := r.db.WithContext(ctx).Select("notes").First(&stored, t.ID).Error; err != nil {
+		return translate(err)
+	}
+	current, err := r.notes.open(ctx, r.db, stored.Notes)
+	if err != nil {
+		return err
+	}
+	row := *t
+	row.Notes = stored.Notes
+	if current != t.Notes {
+		if row.Notes, err = r.notes.seal(ctx, r.db, t.MissionID, t.Notes); err != nil {
+			return err
+		}
+	}
+	if err := r.db.WithContext(ctx).Save(&row).Error; err != nil {
+		return translate(err)
+	}
+	t.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+func (r pgTargets) Delete(ctx context.Context, id uint) error {
+	res := r.db.WithContext(ctx).Delete(&models.Target{}, id)
+	if res.Error != nil {
+		return translate(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r pgTargets) AddNoteRevision(ctx context.Context, rev *models.TargetNoteRevision) error {
+	notes := rev.Notes
+	if r.notes != nil && notes != "" {
+		var missionID uint
+		err := r.db.WithContext(ctx).Model(&models.Target{}).Where("id = ?", rev.TargetID).Pluck("mission_id", &missionID).Error
+		if err != nil {
+			return translate(err)
+		}
+		if missionID == 0 {
+			return ErrNotFound
+		}
+		if notes, err = r.notes.seal(ctx, r.db, missionID, notes); err != nil {
+			return err
+		}
+	}
+	row := r.db.WithContext(ctx).Raw(`INSERT INTO target_note_revisions (target_id, revision, author, notes)
+SELECT ?, COALESCE(MAX(revision), 0) + 1, ?, ?
+FROM target_note_revisions WHERE target_id = ?
+RETURNING id, revision, created_at`, rev.TargetID, rev.Author, notes, rev.TargetID).Row()
+	return translate(row.Scan(&rev.ID, &rev.Revision, &rev.CreatedAt))
+}
+
+func (r pgTargets) NoteRevisions(ctx context.Context, targetID uint) ([]models.TargetNoteRevision, error) {
+	var list []models.TargetNoteRevision
+	if err := r.db.WithContext(ctx).Where("target_id = ?", targetID).Order("revision").Find(&list).Error; err != nil {
+		return nil, translate(err)
+	}
+	for i := range list {
+		var err error
+		if list[i].Notes, err = r.notes.open(ctx, r.db, list[i].Notes); err != nil {
+			return nil, fmt.Errorf("revision %d: %w", list[i].Revision, err)
+		}
+	}
+	return list, nil
+}
+
+type pgAPIKeys struct{ db *gorm.DB }
+
+func (r pgAPIKeys) Create(ctx context.Context, k *models.APIKey) error {
+	return translate(r.db.WithContext(ctx).Create(k).Error)
+}
+
+func (r pgAPIKeys) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var k models.APIKey
+	if err := r.db.WithContext(ctx).Where("prefix = ?", prefix).First(&k).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &k, nil
+}
+
+func (r pgAPIKeys) List(ctx context.Context) ([]models.APIKey, error) {
+	var list []models.APIKey
+	if err := r.db.WithContext(ctx).Order("id").Find(&list).Error; err != nil {
+		return nil, translate(err)
+	}
+	return list, nil
+}
+
+func (r pgAPIKeys) Revoke(ctx context.Context, id uint, at time.Time) error {
+	res := r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at)
+	if res.Error != nil {
+		return translate(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r pgAPIKeys) Touch(ctx context.Context, id uint, at time.Time) error {
+	return translate(r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error)
+}
+
+func orderByID(db *gorm.DB) *gorm.DB { return db.Order("id") }
+
+// keyset orders q by the page's sort (ties broken by id), seeks past the
+// cursor and fetches one row more than the limit so trimPage can tell
+// whether another page follows. Column names come from the sort whitelist.
+func keyset(q *gorm.DB, table string, spec sortSpec, p Page) (*gorm.DB, error) {
+	dir, op := "ASC", ">"
+	if spec.desc {
+		dir, op = "DESC", "<"
+	}
+	if p.Cursor != "" {
+		v, id, err := decodeCursor(p.Cursor, spec)
+		if err != nil {
+			return nil, err
+		}
+		if spec.field == "id" {
+			q = q.Where(fmt.Sprintf("%s.id %s ?", table, op), id)
+		} else {
+			q = q.Where(fmt.Sprintf("(%s.%s, %s.id) %s (?, ?)", table, spec.field, table, op), v, id)
+		}
+	}
+	if spec.field != "id" {
+		q = q.Order(fmt.Sprintf("%s.%s %s", table, spec.field, dir))
+	}
+	return q.Order(fmt.Sprintf("%s.id %s", table, dir)).Limit(p.limit() + 1), nil
+}
+
+// translate maps GORM and Postgres errors onto the package's sentinel errors
+// so callers get the same errors from every Store implementation.
+func translate(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case "23505": // unique_violation
+		switch pgErr.ConstraintName {
+		case "ux_active_mission_per_cat":
+			return ErrCatBusy
+		case "ux_targets_mission_name":
+			return ErrDuplicateTarget
+		}
+	case "23503": // foreign_key_violation
+		if pgErr.ConstraintName == "missions_assigned_cat_id_fkey" {
+			if strings.HasPrefix(pgErr.Message, "update or delete") {
+				return ErrCatHasMissions
+			}
+			return ErrCatNotFound
+		}
+		if pgErr.ConstraintName == "api_keys_cat_id_fkey" {
+			return ErrCatNotFound
+		}
+		if pgErr.ConstraintName == "target_note_revisions_target_id_fkey" {
+			return ErrNotFound
+		}
+		if pgErr.ConstraintName == "cats_breed_fkey" {
+			return ErrUnknownBreed
+		}
+	case "P0001": // raise_exception
+		switch pgErr.Message {
+		case ErrTooManyTargets.Error():
+			return ErrTooManyTargets
+		case ErrNotesFrozen.Error():
+			return ErrNotesFrozen
+		}
+	}
+	return err
+}
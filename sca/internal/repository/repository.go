@@ -0,0 +1,119 @@
+// Package repository hides persistence behind small interfaces so handlers
+// don't depend on *gorm.DB. Postgres is the production implementation and
+// Memory is a database-free one that enforces the same invariants.
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"sca/sca/internal/models"
+)
+
+// MaxTargetsPerMission mirrors the tg_max_3_targets trigger.
+const MaxTargetsPerMission = 3
+
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrCatNotFound     = errors.New("cat not found")
+	ErrCatBusy         = errors.New("cat already assigned to an active mission")
+	ErrCatHasMissions  = errors.New("cat is referenced by missions")
+	ErrNotEditable     = errors.New("mission not editable")
+	ErrTooManyTargets  = errors.New("mission already has 3 targets")
+	ErrDuplicateTarget = errors.New("target with this name already exists in mission")
+	ErrNotesFrozen     = errors.New("target completed; notes frozen")
+	ErrUnknownBreed    = errors.New("breed not in the catalog")
+)
+
+// CatBusyError is ErrCatBusy naming the mission that holds the cat. It is
+// only available when the conflict was found before writing; a violation of
+// ux_active_mission_per_cat itself is plain ErrCatBusy.
+type CatBusyError struct {
+	CatID     uint
+	MissionID uint
+}
+
+func (e *CatBusyError) Error() string {
+	return fmt.Sprintf("cat %d already assigned to active mission %d", e.CatID, e.MissionID)
+}
+
+func (e *CatBusyError) Unwrap() error { return ErrCatBusy }
+
+type CatRepository interface {
+	Create(ctx context.Context, cat *models.Cat) error
+	Get(ctx context.Context, id uint) (*models.Cat, error)
+	// GetForUpdate is Get that also locks the cat row until the surrounding
+	// transaction ends.
+	GetForUpdate(ctx context.Context, id uint) (*models.Cat, error)
+	// List returns one page of cats and the cursor of the next page.
+	List(ctx context.Context, f CatFilter, p Page) ([]models.Cat, string, error)
+	// Update persists the given column values and returns the fresh row.
+	Update(ctx context.Context, id uint, updates map[string]any) (*models.Cat, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type MissionRepository interface {
+	// Create inserts the mission together with its targets.
+	Create(ctx context.Context, m *models.Mission) error
+	// Get returns the mission with its targets.
+	Get(ctx context.Context, id uint) (*models.Mission, error)
+	// GetForUpdate is Get that also locks the mission row until the
+	// surrounding transaction ends, serializing concurrent state changes.
+	GetForUpdate(ctx context.Context, id uint) (*models.Mission, error)
+	// List returns one page of missions (with targets) and the cursor of the next page.
+	List(ctx context.Context, f MissionFilter, p Page) ([]models.Mission, string, error)
+	// Update saves the mission's own columns; targets are left untouched.
+	Update(ctx context.Context, m *models.Mission) error
+	Delete(ctx context.Context, id uint) error
+	// HeldBy returns the assigned or active mission of the cat, or
+	// ErrNotFound when the cat is free.
+	HeldBy(ctx context.Context, catID uint) (*models.Mission, error)
+	AddTransition(ctx context.Context, t *models.MissionTransition) error
+	// Transitions returns the mission's state history, oldest first.
+	Transitions(ctx context.Context, missionID uint) ([]models.MissionTransition, error)
+	AddAssignment(ctx context.Context, a *models.MissionAssignment) error
+	// Assignments returns the mission's cat hand-overs, oldest first.
+	Assignments(ctx context.Context, missionID uint) ([]models.MissionAssignment, error)
+}
+
+type TargetRepository interface {
+	// Add appends targets to an existing mission and returns them with IDs set.
+	Add(ctx context.Context, missionID uint, targets []models.Target) ([]models.Target, error)
+	Get(ctx context.Context, id uint) (*models.Target, error)
+	// Update saves t; changing the notes of a completed target fails with
+	// ErrNotesFrozen.
+	Update(ctx context.Context, t *models.Target) error
+	Delete(ctx context.Context, id uint) error
+	// AddNoteRevision stores r as the next revision of its target's notes,
+	// setting its ID and Revision.
+	AddNoteRevision(ctx context.Context, r *models.TargetNoteRevision) error
+	// NoteRevisions returns the target's notes history, oldest first.
+	NoteRevisions(ctx context.Context, targetID uint) ([]models.TargetNoteRevision, error)
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, k *models.APIKey) error
+	// GetByPrefix returns the key with the given public prefix, revoked or
+	// not.
+	GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	List(ctx context.Context) ([]models.APIKey, error)
+	// Revoke marks the key revoked; revoking twice is ErrNotFound.
+	Revoke(ctx context.Context, id uint, at time.Time) error
+	// Touch records that the key was used at the given time.
+	Touch(ctx context.Context, id uint, at time.Time) error
+}
+
+// Store groups the repositories and lets callers run several operations
+// atomically: everything done through the Store passed to fn is committed
+// only if fn returns nil.
+type Store interface {
+	Cats() CatRepository
+	Missions() MissionRepository
+	Targets() TargetRepository
+	APIKeys() APIKeyRepository
+	Audit() AuditRepository
+	Breeds() BreedRepository
+	Tx(ctx context.Context, fn func(s Store) error) error
+}
@@ -0,0 +1,109 @@
+// Package repotest runs tests against every Store implementation so Memory
+// cannot drift from Postgres.
+//
+// Memory always runs. Postgres runs when SCA_TEST_DATABASE_URL points at a
+// server the tests may create databases on; each test gets a fresh database
+// with every migration applied, dropped again when the test ends. Schemas
+// would be cheaper, but migration 002 looks its trigger up by name across
+// the whole database.
+package repotest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"sca/sca/internal/encryption"
+	"sca/sca/internal/repository"
+	"sca/sca/internal/storage"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DatabaseEnv names the variable holding the Postgres URL or DSN.
+const DatabaseEnv = "SCA_TEST_DATABASE_URL"
+
+// Run calls fn as a subtest for each Store: "memory", "postgres" and
+// "postgres_encrypted", the last one sealing target notes.
+func Run(t *testing.T, fn func(t *testing.T, s repository.Store)) {
+	t.Run("memory", func(t *testing.T) { fn(t, repository.NewMemory()) })
+	t.Run("postgres", func(t *testing.T) { fn(t, repository.NewPostgres(Postgres(t))) })
+	t.Run("postgres_encrypted", func(t *testing.T) {
+		fn(t, repository.NewPostgres(Postgres(t), repository.WithNotesKeys(keyring(t))))
+	})
+}
+
+// Postgres returns a connection to a freshly migrated database, skipping
+// the test when DatabaseEnv is unset.
+func Postgres(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv(DatabaseEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", DatabaseEnv)
+	}
+	admin := open(t, dsn)
+	name := "sca_test_" + hex.EncodeToString(random(t, 8))
+	if err := admin.Exec("CREATE DATABASE " + name).Error; err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP DATABASE " + name).Error; err != nil {
+			t.Errorf("dropping database: %v", err)
+		}
+	})
+
+	db := open(t, withDatabase(dsn, name))
+	m, err := storage.NewMigrator(db, storage.MigrationsFS(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	return db
+}
+
+func open(t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", DatabaseEnv, err)
+	}
+	t.Cleanup(func() { storage.Close(db) })
+	return db
+}
+
+// withDatabase points dsn, a URL or a key=value DSN, at database name.
+func withDatabase(dsn, name string) string {
+	if u, err := url.Parse(dsn); err == nil && strings.HasPrefix(u.Scheme, "postgres") {
+		u.Path = "/" + name
+		return u.String()
+	}
+	return dsn + " dbname=" + name
+}
+
+func keyring(t *testing.T) *encryption.Keyring {
+	t.Helper()
+	key := base64.StdEncoding.EncodeToString(random(t, encryption.KeySize))
+	k, err := encryption.ParseKeyring(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func random(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"sca/sca/internal/models"
+)
+
+// Stats are point-in-time counts for dashboards.
+type Stats struct {
+	MissionsByState map[models.MissionState]int64
+	// IdleCats are cats without an assigned or active mission.
+	IdleCats int64
+	// OpenTargets are targets not yet completed on missions that are not
+	// closed.
+	OpenTargets int64
+}
+
+// StatsReader is implemented by stores that can compute Stats.
+type StatsReader interface {
+	Stats(ctx context.Context) (*Stats, error)
+}
+
+func (p *Postgres) Stats(ctx context.Context) (*Stats, error) {
+	db := p.db.WithContext(ctx)
+	st := &Stats{MissionsByState: map[models.MissionState]int64{}}
+
+	var rows []struct {
+		State models.MissionState
+		N     int64
+	}
+	if err := db.Model(&models.Mission{}).Select("state, count(*) AS n").Group("state").Scan(&rows).Error; err != nil {
+		return nil, translate(err)
+	}
+	for _, r := range rows {
+		st.MissionsByState[r.State] = r.N
+	}
+
+	holding := []models.MissionState{models.StateAssigned, models.StateActive}
+	err := db.Model(&models.Cat{}).
+		Where("NOT EXISTS (SELECT 1 FROM missions m WHERE m.assigned_cat_id = cats.id AND m.state IN ?)", holding).
+		Count(&st.IdleCats).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	open := []models.MissionState{models.StateDraft, models.StateAssigned, models.StateActive}
+	err = db.Model(&models.Target{}).
+		Joins("JOIN missions m ON m.id = targets.mission_id").
+		Where("NOT targets.completed AND m.state IN ?", open).
+		Count(&st.OpenTargets).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return st, nil
+}
+
+func (m *Memory) Stats(context.Context) (*Stats, error) {
+	st := &Stats{MissionsByState: map[models.MissionState]int64{}}
+	err := memStore{m: m}.view(func(s *memState) error {
+		busy := map[uint]bool{}
+		for _, ms := range s.missions {
+			st.MissionsByState[ms.State]++
+			if ms.State.HoldsCat() && ms.AssignedCatID != nil {
+				busy[*ms.AssignedCatID] = true
+			}
+		}
+		st.IdleCats = int64(len(s.cats) - len(busy))
+		for _, t := range s.targets {
+			if !t.Completed && !s.missions[t.MissionID].State.Terminal() {
+				st.OpenTargets++
+			}
+		}
+		return nil
+	})
+	return st, err
+}
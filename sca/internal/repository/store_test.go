@@ -0,0 +1,202 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sca/sca/internal/models"
+	"sca/sca/internal/repository"
+	"sca/sca/internal/repository/repotest"
+)
+
+func newCat(t *testing.T, s repository.Store) *models.Cat {
+	t.Helper()
+	ctx := context.Background()
+	breed := models.Breed{ID: "siam", Name: "Siamese", Source: models.BreedSourceSeed, SyncedAt: time.Now()}
+	if err := s.Breeds().Upsert(ctx, []models.Breed{breed}); err != nil {
+		t.Fatal(err)
+	}
+	cat := &models.Cat{Name: "Tom", Breed: "siam"}
+	if err := s.Cats().Create(ctx, cat); err != nil {
+		t.Fatal(err)
+	}
+	return cat
+}
+
+func newMission(t *testing.T, s repository.Store, m models.Mission) *models.Mission {
+	t.Helper()
+	if m.State == "" {
+		m.State = models.StateDraft
+	}
+	if err := s.Missions().Create(context.Background(), &m); err != nil {
+		t.Fatal(err)
+	}
+	return &m
+}
+
+func targets(names ...string) []models.Target {
+	var out []models.Target
+	for _, n := range names {
+		out = append(out, models.Target{Name: n, Country: "UA"})
+	}
+	return out
+}
+
+func TestOneActiveMissionPerCat(t *testing.T) {
+	repotest.Run(t, func(t *testing.T, s repository.Store) {
+		ctx := context.Background()
+		cat := newCat(t, s)
+		first := newMission(t, s, models.Mission{AssignedCatID: &cat.ID, State: models.StateAssigned, Targets: targets("alpha")})
+
+		second := models.Mission{AssignedCatID: &cat.ID, State: models.StateActive, Targets: targets("beta")}
+		if err := s.Missions().Create(ctx, &second); !errors.Is(err, repository.ErrCatBusy) {
+			t.Fatalf("create: got %v, want ErrCatBusy", err)
+		}
+
+		draft := newMission(t, s, models.Mission{AssignedCatID: &cat.ID, Targets: targets("gamma")})
+		draft.State = models.StateAssigned
+		if err := s.Missions().Update(ctx, draft); !errors.Is(err, repository.ErrCatBusy) {
+			t.Fatalf("update: got %v, want ErrCatBusy", err)
+		}
+
+		held, err := s.Missions().HeldBy(ctx, cat.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if held.ID != first.ID {
+			t.Fatalf("cat held by mission %d, want %d", held.ID, first.ID)
+		}
+
+		first.State = models.StateCompleted
+		if err := s.Missions().Update(ctx, first); err != nil {
+			t.Fatal(err)
+		}
+		draft.State = models.StateAssigned
+		if err := s.Missions().Update(ctx, draft); err != nil {
+			t.Fatalf("cat still busy after its mission completed: %v", err)
+		}
+	})
+}
+
+func TestAtMostThreeTargets(t *testing.T) {
+	repotest.Run(t, func(t *testing.T, s repository.Store) {
+		ctx := context.Background()
+		m := models.Mission{State: models.StateDraft, Targets: targets("a1", "a2", "a3", "a4")}
+		if err := s.Missions().Create(ctx, &m); !errors.Is(err, repository.ErrTooManyTargets) {
+			t.Fatalf("create: got %v, want ErrTooManyTargets", err)
+		}
+
+		mission := newMission(t, s, models.Mission{Targets: targets("a1", "a2")})
+		if _, err := s.Targets().Add(ctx, mission.ID, targets("a3", "a4")); !errors.Is(err, repository.ErrTooManyTargets) {
+			t.Fatalf("add: got %v, want ErrTooManyTargets", err)
+		}
+		if _, err := s.Targets().Add(ctx, mission.ID, targets("a3")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Targets().Add(ctx, mission.ID, targets("a4")); !errors.Is(err, repository.ErrTooManyTargets) {
+			t.Fatalf("add to full mission: got %v, want ErrTooManyTargets", err)
+		}
+
+		got, err := s.Missions().Get(ctx, mission.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Targets) != repository.MaxTargetsPerMission {
+			t.Fatalf("%d targets, want %d", len(got.Targets), repository.MaxTargetsPerMission)
+		}
+	})
+}
+
+func TestUniqueTargetNames(t *testing.T) {
+	repotest.Run(t, func(t *testing.T, s repository.Store) {
+		ctx := context.Background()
+		m := models.Mission{State: models.StateDraft, Targets: targets("alpha", "alpha")}
+		if err := s.Missions().Create(ctx, &m); !errors.Is(err, repository.ErrDuplicateTarget) {
+			t.Fatalf("create: got %v, want ErrDuplicateTarget", err)
+		}
+
+		mission := newMission(t, s, models.Mission{Targets: targets("alpha", "beta")})
+		if _, err := s.Targets().Add(ctx, mission.ID, targets("alpha")); !errors.Is(err, repository.ErrDuplicateTarget) {
+			t.Fatalf("add: got %v, want ErrDuplicateTarget", err)
+		}
+
+		beta := mission.Targets[1]
+		beta.Name = "alpha"
+		if err := s.Targets().Update(ctx, &beta); !errors.Is(err, repository.ErrDuplicateTarget) {
+			t.Fatalf("rename: got %v, want ErrDuplicateTarget", err)
+		}
+
+		// Names only have to be unique within a mission.
+		newMission(t, s, models.Mission{Targets: targets("alpha")})
+	})
+}
+
+func TestCompletedTargetNotesFrozen(t *testing.T) {
+	repotest.Run(t, func(t *testing.T, s repository.Store) {
+		ctx := context.Background()
+		mission := newMission(t, s, models.Mission{Targets: []models.Target{{Name: "alpha", Country: "UA", Notes: "seen at the docks"}}})
+
+		target, err := s.Targets().Get(ctx, mission.Targets[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target.Completed = true
+		if err := s.Targets().Update(ctx, target); err != nil {
+			t.Fatal(err)
+		}
+
+		// Saving a completed target with its notes unchanged is fine.
+		target.Country = "PL"
+		if err := s.Targets().Update(ctx, target); err != nil {
+			t.Fatalf("update without notes change: %v", err)
+		}
+
+		target.Notes = "left town"
+		if err := s.Targets().Update(ctx, target); !errors.Is(err, repository.ErrNotesFrozen) {
+			t.Fatalf("got %v, want ErrNotesFrozen", err)
+		}
+
+		got, err := s.Targets().Get(ctx, target.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Notes != "seen at the docks" || got.Country != "PL" {
+			t.Fatalf("got notes %q, country %q", got.Notes, got.Country)
+		}
+	})
+}
+
+func TestCreateCatNeedsCatalogBreed(t *testing.T) {
+	repotest.Run(t, func(t *testing.T, s repository.Store) {
+		cat := models.Cat{Name: "Tom", Breed: "nope"}
+		if err := s.Cats().Create(context.Background(), &cat); !errors.Is(err, repository.ErrUnknownBreed) {
+			t.Fatalf("got %v, want ErrUnknownBreed", err)
+		}
+	})
+}
+
+func TestTxRollsBack(t *testing.T) {
+	repotest.Run(t, func(t *testing.T, s repository.Store) {
+		ctx := context.Background()
+		mission := newMission(t, s, models.Mission{Targets: targets("alpha")})
+		boom := errors.New("boom")
+		err := s.Tx(ctx, func(s repository.Store) error {
+			if _, err := s.Targets().Add(ctx, mission.ID, targets("beta")); err != nil {
+				return err
+			}
+			return boom
+		})
+		if !errors.Is(err, boom) {
+			t.Fatalf("got %v, want boom", err)
+		}
+		got, err := s.Missions().Get(ctx, mission.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Targets) != 1 {
+			t.Fatalf("%d targets after rollback, want 1", len(got.Targets))
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"sca/sca/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCaptureBytes bounds how much of a body is buffered for logging. JSON
+// bodies have to be parsed whole to be redacted, so larger ones are not
+// logged at all.
+const maxCaptureBytes = 1 << 20
+
+type BodyLogConfig struct {
+	// MaxBytes is how much of each body is logged; the rest is cut off.
+	MaxBytes int
+	// Redact lists the JSON paths to hide, see logging.Redactor.
+	Redact []string
+	// SkipPaths are URL path prefixes whose bodies are never logged.
+	SkipPaths []string
+}
+
+// BodyLogger logs request and response bodies at debug level. Only JSON and
+// text bodies are logged; binary and streamed responses (event streams or
+// anything flushed early) are skipped. JSON is redacted before it is cut to
+// MaxBytes.
+func BodyLogger(cfg BodyLogConfig) gin.HandlerFunc {
+	redactor := logging.NewRedactor(cfg.Redact)
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		log := logging.From(ctx)
+		if !log.Enabled(ctx, slog.LevelDebug) || skipPath(c.Request.URL.Path, cfg.SkipPaths) {
+			c.Next()
+			return
+		}
+
+		var reqBody []byte
+		reqOverflow := false
+		if c.Request.Body != nil && loggableType(c.ContentType()) {
+			reqBody, reqOverflow = peekBody(c.Request)
+		}
+		w := &bodyCapture{ResponseWriter: c.Writer}
+		c.Writer = w
+
+		c.Next()
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", w.Status()),
+		}
+		if s := renderBody(redactor, cfg.MaxBytes, c.ContentType(), reqBody, reqOverflow); s != "" {
+			attrs = append(attrs, slog.String("request_body", s))
+		}
+		if w.streamed {
+			attrs = append(attrs, slog.String("response_body", "[streamed, not logged]"))
+		} else if s := renderBody(redactor, cfg.MaxBytes, w.Header().Get("Content-Type"), w.buf.Bytes(), w.overflow); s != "" {
+			attrs = append(attrs, slog.String("response_body", s))
+		}
+		log.LogAttrs(ctx, slog.LevelDebug, "http body", attrs...)
+	}
+}
+
+func skipPath(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// loggableType reports whether bodies of this media type are JSON or text.
+func loggableType(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mt == "text/event-stream":
+		return false
+	case isJSON(mt), strings.HasPrefix(mt, "text/"):
+		return true
+	}
+	return false
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// peekBody reads up to maxCaptureBytes of the request body and puts it
+// back so handlers still see all of it.
+func peekBody(r *http.Request) ([]byte, bool) {
+	buf, err := io.ReadAll(io.LimitReader(r.Body, maxCaptureBytes+1))
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+	if err != nil {
+		return nil, false
+	}
+	if len(buf) > maxCaptureBytes {
+		return nil, true
+	}
+	return buf, false
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// renderBody turns a captured body into its log form: redacted when JSON,
+// cut to maxBytes, or a placeholder when it can't be shown safely.
+func renderBody(r *logging.Redactor, maxBytes int, contentType string, body []byte, overflow bool) string {
+	if overflow {
+		return fmt.Sprintf("[larger than %d bytes, not logged]", maxCaptureBytes)
+	}
+	if len(body) == 0 || !loggableType(contentType) {
+		return ""
+	}
+	if mt, _, _ := mime.ParseMediaType(contentType); isJSON(mt) {
+		redacted, ok := r.RedactJSON(body)
+		if !ok {
+			return "[invalid JSON, not logged]"
+		}
+		body = redacted
+	}
+	if maxBytes > 0 && len(body) > maxBytes {
+		cut := body[:maxBytes]
+		for len(cut) > 0 && !utf8.Valid(cut) {
+			cut = cut[:len(cut)-1]
+		}
+		return fmt.Sprintf("%s…[truncated, %d bytes total]", cut, len(body))
+	}
+	return string(body)
+}
+
+// bodyCapture tees what the handler writes into buf, unless the response
+// is not JSON or text, and notes whether the handler streamed.
+type bodyCapture struct {
+	gin.ResponseWriter
+	buf      bytes.Buffer
+	overflow bool
+	skip     bool
+	checked  bool
+	streamed bool
+}
+
+func (w *bodyCapture) capture(b []byte) {
+	if !w.checked {
+		w.checked = true
+		w.skip = !loggableType(w.Header().Get("Content-Type"))
+	}
+	if w.skip || w.overflow {
+		return
+	}
+	if w.buf.Len()+len(b) > maxCaptureBytes {
+		w.overflow = true
+		w.buf.Reset()
+		return
+	}
+	w.buf.Write(b)
+}
+
+func (w *bodyCapture) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyCapture) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *bodyCapture) Flush() {
+	w.streamed = true
+	w.ResponseWriter.Flush()
+}
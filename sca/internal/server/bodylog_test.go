@@ -0,0 +1,67 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sca/sca/internal/config"
+	"sca/sca/internal/handlers"
+	"sca/sca/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLogRedactsNotesByDefault(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	h := handlers.New(repository.NewMemory())
+	r := gin.New()
+	r.Use(RequestID(log), BodyLogger(BodyLogConfig{
+		MaxBytes: 1 << 16,
+		Redact:   config.Default().Log.BodyRedact,
+	}))
+	r.POST("/missions", h.CreateMission)
+	r.PATCH("/missions/:id/targets/:tid", h.UpdateTarget)
+	r.GET("/missions/:id/targets/:tid/notes/history", h.ListNoteRevisions)
+	r.GET("/missions/:id/targets/:tid/notes/diff", h.DiffNotes)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code >= http.StatusMultipleChoices {
+			t.Fatalf("%s %s: %d %s", method, path, w.Code, w.Body)
+		}
+		return w
+	}
+
+	// Target and mission both get ID 1 in a fresh Memory store.
+	send("POST", "/missions", `{"targets":[{"name":"alpha","country":"UA","notes":"seen at the docks"}]}`)
+	target := "/missions/1/targets/1"
+	send("PATCH", target, `{"notes":"seen at the docks\nleft town at dawn"}`)
+	send("GET", target+"/notes/history", "")
+	diff := send("GET", target+"/notes/diff", "")
+	if !strings.Contains(diff.Body.String(), "left town") {
+		t.Fatalf("diff response lacks the notes: %s", diff.Body)
+	}
+
+	if n := strings.Count(logs.String(), `"msg":"http body"`); n != 4 {
+		t.Fatalf("%d bodies logged, want 4:\n%s", n, logs.String())
+	}
+	for _, secret := range []string{"docks", "left town"} {
+		if strings.Contains(logs.String(), secret) {
+			t.Errorf("notes text %q logged:\n%s", secret, logs.String())
+		}
+	}
+	if !strings.Contains(logs.String(), `\"unified\":\"[REDACTED]\"`) {
+		t.Errorf("unified diff not redacted:\n%s", logs.String())
+	}
+}
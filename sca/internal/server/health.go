@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sca/sca/internal/breeds"
+	"sca/sca/internal/clients/thecatapi"
+	"sca/sca/internal/logging"
+	"sca/sca/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Check is one dependency probed by /readyz.
+type Check struct {
+	Name string
+	// Critical checks make /readyz answer 503 when they fail; the others
+	// are only reported.
+	Critical bool
+	// Run returns optional details to show and an error when the check
+	// fails.
+	Run func(ctx context.Context) (any, error)
+}
+
+// CheckResult is the outcome of one Check in the /readyz body.
+type CheckResult struct {
+	OK        bool    `json:"ok"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Detail    any     `json:"detail,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Readiness is the /readyz body.
+type Readiness struct {
+	OK     bool                   `json:"ok"`
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Health tracks whether the instance should receive traffic.
+type Health struct {
+	draining atomic.Bool
+	timeout  time.Duration
+	checks   []Check
+}
+
+// NewHealth returns a Health running checks for /readyz, each bounded by
+// timeout.
+func NewHealth(timeout time.Duration, checks ...Check) *Health {
+	return &Health{timeout: timeout, checks: checks}
+}
+
+// SetDraining marks the instance as shutting down.
+func (h *Health) SetDraining() { h.draining.Store(true) }
+
+func (h *Health) Draining() bool { return h.draining.Load() }
+
+// Healthz answers 200 {"ok": true}, or 503 once shutdown has started. It
+// predates /livez and /readyz and is kept for existing probes.
+func (h *Health) Healthz(c *gin.Context) {
+	if h.Draining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"ok": false, "status": "shutting down"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Livez answers 200 while the process can serve HTTP at all; it checks no
+// dependencies, so a database outage does not get the instance restarted.
+func (h *Health) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Readyz runs every check concurrently and answers 200 with the per-check
+// breakdown, or 503 when a critical check failed or shutdown has started.
+func (h *Health) Readyz(c *gin.Context) {
+	if h.Draining() {
+		c.JSON(http.StatusServiceUnavailable, Readiness{Status: "shutting down"})
+		return
+	}
+	res := h.Check(c.Request.Context())
+	status := http.StatusOK
+	if !res.OK {
+		status = http.StatusServiceUnavailable
+		log := logging.From(c.Request.Context())
+		for name, r := range res.Checks {
+			if !r.OK && r.Critical {
+				log.Warn("readiness check failed", "check", name, "err", r.Error)
+			}
+		}
+	}
+	c.JSON(status, res)
+}
+
+// Check runs the checks and summarizes them.
+func (h *Health) Check(ctx context.Context) Readiness {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	results := make([]CheckResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, chk := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			detail, err := chk.Run(ctx)
+			r := CheckResult{
+				OK:        err == nil,
+				Critical:  chk.Critical,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+				Detail:    detail,
+			}
+			if err != nil {
+				r.Error = err.Error()
+			}
+			results[i] = r
+		}()
+	}
+	wg.Wait()
+
+	out := Readiness{OK: true, Status: "ok", Checks: make(map[string]CheckResult, len(results))}
+	for i, r := range results {
+		out.Checks[h.checks[i].Name] = r
+		if !r.OK && r.Critical {
+			out.OK, out.Status = false, "unavailable"
+		} else if !r.OK && out.OK {
+			out.Status = "degraded"
+		}
+	}
+	return out
+}
+
+// DatabaseCheck pings the connection pool.
+func DatabaseCheck(db *gorm.DB) Check {
+	return Check{Name: "database", Critical: true, Run: func(ctx context.Context) (any, error) {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		stats := sqlDB.Stats()
+		detail := gin.H{"open_connections": stats.OpenConnections, "in_use": stats.InUse, "idle": stats.Idle}
+		return detail, sqlDB.PingContext(ctx)
+	}}
+}
+
+// MigrationsCheck verifies the newest migration the binary knows about has
+// been applied.
+func MigrationsCheck(m *storage.Migrator) Check {
+	return Check{Name: "migrations", Critical: true, Run: func(ctx context.Context) (any, error) {
+		latest, ok := m.Latest()
+		if !ok {
+			return nil, nil
+		}
+		return gin.H{"latest": latest.Version}, m.VerifyLatest(ctx)
+	}}
+}
+
+var (
+	errBreedRefresh = errors.New("last breed refresh failed")
+	errNoBreeds     = errors.New("breed catalog is empty")
+	errBreedSync    = errors.New("last breed sync failed")
+)
+
+// BreedCatalogCheck reports the local breed catalog. It is not critical:
+// an empty catalog only fails cat creation.
+func BreedCatalogCheck(s *breeds.Syncer) Check {
+	return Check{Name: "breed_catalog", Run: func(ctx context.Context) (any, error) {
+		st, err := s.Status(ctx)
+		switch {
+		case err != nil:
+			return nil, err
+		case st.Breeds == 0:
+			return st, errNoBreeds
+		case st.LastError != "":
+			return st, errBreedSync
+		}
+		return st, nil
+	}}
+}
+
+// BreedCacheCheck reports the TheCatAPI breed cache. It is not critical:
+// the cache only feeds the breed catalog sync.
+func BreedCacheCheck(c *thecatapi.HTTPClient) Check {
+	return Check{Name: "breed_cache", Run: func(context.Context) (any, error) {
+		st := c.CacheState()
+		if st.LastError != "" {
+			return st, errBreedRefresh
+		}
+		return st, nil
+	}}
+}
@@ -1,67 +1,113 @@
 package server
 
 import (
-	"fmt"
-	"os"
-	"time"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
 
+	"sca/sca/internal/auth"
+	"sca/sca/internal/config"
 	"sca/sca/internal/handlers"
-	"sca/sca/internal/storage"
+	"sca/sca/internal/logging"
+	"sca/sca/internal/metrics"
+	"sca/sca/internal/problem"
+	"sca/sca/internal/repository"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
 	// swagger docs
 	_ "sca/docs"
-
-	"gorm.io/gorm"
 )
 
-func Router(db *gorm.DB) *gin.Engine {
-	storage.MustRunMigrations(db)
-
+// Router builds the API. authn guards /api/v1; nil leaves it open.
+func Router(cfg *config.Config, store repository.Store, health *Health, authn *auth.Authenticator) *gin.Engine {
 	r := gin.New()
-	if os.Getenv("APP_ENV") == "dev" {
-		r.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-			return fmt.Sprintf("{\"time\":\"%s\",\"status\":%d,\"method\":\"%s\",\"path\":\"%s\",\"latency\":\"%s\"}\n",
-				param.TimeStamp.Format(time.RFC3339),
-				param.StatusCode,
-				param.Method,
-				param.Path,
-				param.Latency,
-			)
+	// Without trusted proxies ClientIP is the peer address, so callers
+	// can't put a forged X-Forwarded-For into logs and the audit trail.
+	if err := r.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		panic(err) // config.Validate checks the list
+	}
+	r.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithGinFilter(traced)))
+	r.Use(RequestID(slog.Default()), AccessLog())
+	if cfg.Metrics.Enabled {
+		r.Use(Metrics())
+	}
+	if *cfg.Log.Bodies {
+		r.Use(BodyLogger(BodyLogConfig{
+			MaxBytes:  cfg.Log.BodyMaxBytes,
+			Redact:    cfg.Log.BodyRedact,
+			SkipPaths: []string{"/swagger/", "/metrics"},
 		}))
-		r.Use(ResponseLogger())
 	}
-	r.Use(gin.Recovery())
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		logging.From(c.Request.Context()).Error("panic", "err", err, "stack", string(debug.Stack()))
+		problem.Write(c, problem.New(http.StatusInternalServerError, problem.CodeInternal, "internal server error"))
+	}))
+	r.NoRoute(func(c *gin.Context) {
+		problem.Write(c, problem.New(http.StatusNotFound, problem.CodeRouteNotFound, "no such route"))
+	})
 
-	r.GET("/healthz", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
+	r.GET("/healthz", health.Healthz)
+	r.GET("/livez", health.Livez)
+	r.GET("/readyz", health.Readyz)
+	if cfg.Metrics.Enabled {
+		r.GET("/metrics", gin.WrapH(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})))
+	}
 
 	v1 := r.Group("/api/v1")
+	if authn != nil {
+		v1.Use(authn.Middleware())
+	}
+	v1.Use(AuditSource())
 	{
-		h := handlers.New(db)
+		h := handlers.New(store,
+			handlers.WithCompletionPolicy(handlers.CompletionPolicy{
+				AutoComplete:       cfg.Missions.AutoComplete,
+				RequireTargetsDone: cfg.Missions.RequireTargetsDone,
+			}))
+
+		// Each route names the permissions that admit a caller, see
+		// auth.Require; handlers narrow further to the caller's own rows.
+		catsRead := auth.Require(auth.PermCatsRead)
+		missionsRead := auth.Require(auth.PermMissionsRead, auth.PermMissionsReadOwn)
+		missionsWrite := auth.Require(auth.PermMissionsWrite)
 
 		// Cats
-		v1.POST("/cats", h.CreateCat)
-		v1.GET("/cats", h.ListCats)
-		v1.GET("/cats/:id", h.GetCat)
-		v1.PATCH("/cats/:id", h.UpdateCat) 
-		v1.DELETE("/cats/:id", h.DeleteCat)
-		v1.GET("/breeds", h.ListBreeds)
+		v1.POST("/cats", auth.Require(auth.PermCatsWrite), h.CreateCat)
+		v1.GET("/cats", catsRead, h.ListCats)
+		v1.GET("/cats/:id", catsRead, h.GetCat)
+		v1.PATCH("/cats/:id", auth.Require(auth.PermCatsWrite, auth.PermCatsSalary), h.UpdateCat)
+		v1.DELETE("/cats/:id", auth.Require(auth.PermCatsWrite), h.DeleteCat)
+		v1.GET("/breeds", auth.Require(auth.PermBreedsRead), h.ListBreeds)
+		v1.GET("/breeds/:id", auth.Require(auth.PermBreedsRead), h.GetBreed)
 
 		// Missions
-		v1.POST("/missions", h.CreateMission)
-		v1.GET("/missions", h.ListMissions)
-		v1.GET("/missions/:id", h.GetMission)
-		v1.PATCH("/missions/:id", h.UpdateMission)
-		v1.DELETE("/missions/:id", h.DeleteMission)
-		v1.POST("/missions/:id/assign_cat", h.AssignCat)
+		v1.POST("/missions", missionsWrite, h.CreateMission)
+		v1.GET("/missions", missionsRead, h.ListMissions)
+		v1.GET("/missions/:id", missionsRead, h.GetMission)
+		v1.PATCH("/missions/:id", missionsWrite, h.UpdateMission)
+		v1.DELETE("/missions/:id", missionsWrite, h.DeleteMission)
+		v1.POST("/missions/:id/assign_cat", missionsWrite, h.AssignCat)
+		v1.DELETE("/missions/:id/assign_cat", missionsWrite, h.UnassignCat)
+		v1.POST("/missions/:id/reassign_cat", missionsWrite, h.ReassignCat)
+		v1.GET("/missions/:id/assignments", missionsRead, h.ListAssignments)
+		v1.POST("/missions/:id/transitions", missionsWrite, h.TransitionMission)
+		v1.GET("/missions/:id/transitions", missionsRead, h.ListTransitions)
 
 		// Targets
-		v1.POST("/missions/:id/targets", h.AddTargets)
-		v1.PATCH("/missions/:id/targets/:tid", h.UpdateTarget)
-		v1.DELETE("/missions/:id/targets/:tid", h.DeleteTarget)
+		v1.POST("/missions/:id/targets", missionsWrite, h.AddTargets)
+		v1.PATCH("/missions/:id/targets/:tid", auth.Require(auth.PermTargetsWrite, auth.PermTargetsWriteOwn), h.UpdateTarget)
+		v1.DELETE("/missions/:id/targets/:tid", missionsWrite, h.DeleteTarget)
+		v1.GET("/missions/:id/targets/:tid/notes/history", missionsRead, h.ListNoteRevisions)
+		v1.GET("/missions/:id/targets/:tid/notes/diff", missionsRead, h.DiffNotes)
+
+		// Audit
+		v1.GET("/audit", auth.Require(auth.PermAuditRead), h.ListAudit)
 	}
 
 	// Swagger
@@ -69,3 +115,12 @@ func Router(db *gorm.DB) *gin.Engine {
 
 	return r
 }
+
+// traced leaves probes, metrics scrapes and Swagger out of tracing.
+func traced(c *gin.Context) bool {
+	switch c.FullPath() {
+	case "/healthz", "/livez", "/readyz", "/metrics", "/swagger/*any":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sca/sca/internal/config"
+	"sca/sca/internal/models"
+	"sca/sca/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientIPIgnoresUntrustedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		name    string
+		proxies []string
+		want    string
+	}{
+		{"no trusted proxies", nil, "192.0.2.1"},
+		{"peer is a trusted proxy", []string{"192.0.2.0/24"}, "203.0.113.9"},
+		{"peer is another host", []string{"198.51.100.7"}, "192.0.2.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := config.Default()
+			off := false
+			cfg.Log.Bodies = &off
+			cfg.HTTP.TrustedProxies = tc.proxies
+			store := repository.NewMemory()
+			breed := models.Breed{ID: "siam", Name: "Siamese", Source: models.BreedSourceSeed, SyncedAt: time.Now()}
+			if err := store.Breeds().Upsert(ctx, []models.Breed{breed}); err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest("POST", "/api/v1/cats", strings.NewReader(`{"name":"Tom","breed":"siam"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Forwarded-For", "203.0.113.9")
+			req.RemoteAddr = "192.0.2.1:40000"
+			w := httptest.NewRecorder()
+			Router(cfg, store, NewHealth(time.Second), nil).ServeHTTP(w, req)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+
+			events, _, err := store.Audit().List(ctx, repository.AuditFilter{}, repository.Page{Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 1 || events[0].IP != tc.want {
+				t.Fatalf("audit events %+v, want one from %s", events, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"sca/sca/internal/auth"
+	"sca/sca/internal/breeds"
+	"sca/sca/internal/clients/thecatapi"
+	"sca/sca/internal/config"
+	"sca/sca/internal/encryption"
+	"sca/sca/internal/metrics"
+	"sca/sca/internal/repository"
+	"sca/sca/internal/storage"
+	"sca/sca/internal/tracing"
+
+	"gorm.io/gorm"
+)
+
+// Server is the API's HTTP server.
+type Server struct {
+	cfg       config.HTTP
+	health    *Health
+	http      *http.Server
+	breeds    *breeds.Syncer
+	breedSync time.Duration
+}
+
+func New(cfg *config.Config, db *gorm.DB) (*Server, error) {
+	migrator, err := storage.NewMigrator(db, storage.MigrationsFS(cfg.Database.MigrationsDir))
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, err
+	}
+	keys, err := encryption.ParseKeyring(cfg.Encryption.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("encryption.keys: %w", err)
+	}
+	if keys == nil {
+		slog.Warn("no encryption keys configured; target notes are stored in plaintext")
+	}
+	store := repository.NewPostgres(db, repository.WithNotesKeys(keys))
+	if cfg.Metrics.Enabled {
+		if err := metrics.InstrumentDB(db); err != nil {
+			return nil, err
+		}
+		if err := metrics.RegisterDomain(store); err != nil {
+			return nil, err
+		}
+	}
+	var authn *auth.Authenticator
+	if cfg.Auth.Enabled {
+		verifier, err := auth.NewJWTVerifier(cfg.Auth)
+		if err != nil {
+			return nil, err
+		}
+		authn = auth.NewAuthenticator(store.APIKeys(), verifier)
+	} else {
+		slog.Warn("authentication disabled; /api/v1 is open to anyone")
+	}
+	catAPI := thecatapi.NewHTTP(cfg.TheCatAPI)
+	syncer := breeds.NewSyncer(store.Breeds(), catAPI)
+	health := NewHealth(cfg.HTTP.ReadinessTimeout,
+		DatabaseCheck(db),
+		MigrationsCheck(migrator),
+		BreedCatalogCheck(syncer),
+		BreedCacheCheck(catAPI),
+	)
+	return &Server{
+		cfg:       cfg.HTTP,
+		health:    health,
+		breeds:    syncer,
+		breedSync: cfg.Breeds.SyncInterval,
+		http: &http.Server{
+			Addr:              cfg.HTTP.Addr,
+			Handler:           Router(cfg, store, health, authn),
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			ReadTimeout:       cfg.HTTP.ReadTimeout,
+			WriteTimeout:      cfg.HTTP.WriteTimeout,
+			IdleTimeout:       cfg.HTTP.IdleTimeout,
+			ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+		},
+	}, nil
+}
+
+// Run serves until ctx is cancelled, then shuts down gracefully: /healthz
+// and /readyz turn not-ready, after ShutdownDelay the listener closes and in-flight
+// requests get ShutdownTimeout to finish before their connections are
+// dropped. The periodic breed sync is stopped and waited for as well. It
+// returns nil after a clean shutdown.
+func (s *Server) Run(ctx context.Context) error {
+	if n, err := s.breeds.SeedIfEmpty(ctx); err != nil {
+		slog.Error("seeding breeds failed", "err", err)
+	} else if n > 0 {
+		slog.Info("breed catalog seeded", "count", n)
+	}
+
+	// Joined on return so that the caller may close the database right
+	// after Run.
+	var bg sync.WaitGroup
+	bgCtx, stop := context.WithCancel(ctx)
+	defer bg.Wait()
+	defer stop()
+	if s.breedSync > 0 {
+		bg.Add(1)
+		go func() {
+			defer bg.Done()
+			s.breeds.Run(bgCtx, s.breedSync)
+		}()
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", s.cfg.Addr)
+		errc <- s.http.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", "delay", s.cfg.ShutdownDelay, "timeout", s.cfg.ShutdownTimeout)
+	s.health.SetDraining()
+	time.Sleep(s.cfg.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	if err := s.http.Shutdown(shutdownCtx); err != nil {
+		s.http.Close()
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("server stopped")
+	return nil
+}
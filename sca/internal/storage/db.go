@@ -1,51 +1,66 @@
 package storage
 
 import (
-	"fmt"
-	"log"
-	"os"
+	"context"
+	"io/fs"
+	"log/slog"
+	"net/url"
+
+	"sca/sca/internal/config"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func MustInitDBFromEnv() *gorm.DB {
-	host := envOr("POSTGRES_HOST", "localhost")
-	port := envOr("POSTGRES_PORT", "5432")
-	user := envOr("POSTGRES_USER", "sca")
-	pass := envOr("POSTGRES_PASSWORD", "sca")
-	dbname := envOr("POSTGRES_DB", "sca")
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", host, user, pass, dbname, port)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// MustInitDB opens the connection pool described by cfg.
+func MustInitDB(cfg config.Database) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{Logger: NewGormLogger(cfg.SlowQuery)})
+	if err != nil {
+		panic(err)
+	}
+	sqlDB, err := db.DB()
 	if err != nil {
 		panic(err)
 	}
-	log.Println("DB connected")
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	if cfg.URL != "" {
+		slog.Info("DB connected", "url", dbHost(cfg.URL))
+	} else {
+		slog.Info("DB connected", "host", cfg.Host, "port", cfg.Port, "db", cfg.Name)
+	}
 	return db
 }
 
-func MustRunMigrations(db *gorm.DB) {
-	// run raw SQL migrations in order
-    files := []string{
-        "migrations/001_init.sql",
-        "migrations/002_constraints.sql",
-        "migrations/003_targets_unique_name.sql",
-    }
-	for _, f := range files {
-		b, err := os.ReadFile(f)
-		if err != nil {
-			panic(err)
-		}
-		if err := db.Exec(string(b)).Error; err != nil {
-			panic(err)
-		}
+// dbHost returns the host and path of a database URL, leaving out the
+// credentials.
+func dbHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
 	}
-	log.Println("migrations applied")
+	return u.Host + u.Path
 }
 
-func envOr(k, d string) string {
-	if v := os.Getenv(k); v != "" {
-		return v
+// Close closes the connection pool behind db.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// MustRunMigrations applies every pending migration from migrations.
+func MustRunMigrations(db *gorm.DB, migrations fs.FS) {
+	m, err := NewMigrator(db, migrations)
+	if err != nil {
+		panic(err)
+	}
+	n, err := m.Up(context.Background())
+	if err != nil {
+		panic(err)
 	}
-	return d
+	slog.Info("migrations up to date", "applied", n)
 }
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"sca/sca/internal/logging"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger sends GORM's output to the logger in the query's context (see
+// logging.From), so SQL shows up under the request that ran it. Failed and
+// slow queries log at warn, all others at debug. Failures are not errors
+// here: constraint violations are expected and become 409s, and real
+// failures are logged again by the handler.
+type GormLogger struct {
+	slow   time.Duration
+	silent bool
+}
+
+func NewGormLogger(slow time.Duration) *GormLogger { return &GormLogger{slow: slow} }
+
+// LogMode only distinguishes Silent from the rest; levels are up to slog.
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	c := *l
+	c.silent = level == gormlogger.Silent
+	return &c
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, slog.LevelInfo, msg, args)
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, slog.LevelWarn, msg, args)
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, slog.LevelError, msg, args)
+}
+
+func (l *GormLogger) log(ctx context.Context, level slog.Level, msg string, args []any) {
+	if !l.silent {
+		logging.From(ctx).Log(ctx, level, "gorm: "+msg, "args", args)
+	}
+}
+
+// ParamsFilter drops the bound values, so logged SQL keeps its
+// placeholders instead of showing salaries, notes or key hashes.
+func (l *GormLogger) ParamsFilter(_ context.Context, sql string, _ ...any) (string, []any) {
+	return sql, nil
+}
+
+// unfilled matches what the Postgres dialect's Explain leaves of a
+// placeholder it had no value for: $1 becomes $1$.
+var unfilled = regexp.MustCompile(`\$(\d+)\$`)
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	level, msg := slog.LevelDebug, "query"
+	switch {
+	case failed:
+		level, msg = slog.LevelWarn, "query failed"
+	case l.slow > 0 && elapsed > l.slow:
+		level, msg = slog.LevelWarn, "slow query"
+	}
+	log := logging.From(ctx)
+	if !log.Enabled(ctx, level) {
+		return
+	}
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", unfilled.ReplaceAllString(sql, "$$$1")),
+		slog.Int64("rows", rows),
+		slog.Duration("elapsed", elapsed),
+	}
+	if failed {
+		attrs = append(attrs, slog.Any("err", err))
+	}
+	log.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"sca/sca/internal/logging"
+	"sca/sca/internal/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestGormLoggerLeavesValuesOut(t *testing.T) {
+	var logs bytes.Buffer
+	ctx := logging.NewContext(context.Background(),
+		slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	// A dry run builds and logs the SQL without a database.
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{
+		Logger:                 NewGormLogger(0),
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.WithContext(ctx).Create(&models.Cat{Name: "Tom", Breed: "siam", SalaryCents: 987654})
+	db.WithContext(ctx).Where("key_hash = ?", "5f2b9c").First(&models.APIKey{})
+
+	out := logs.String()
+	if strings.Count(out, `"msg":"query"`) != 2 {
+		t.Fatalf("queries not logged:\n%s", out)
+	}
+	for _, v := range []string{"987654", "5f2b9c", "Tom"} {
+		if strings.Contains(out, v) {
+			t.Errorf("bound value %q logged:\n%s", v, out)
+		}
+	}
+	if !strings.Contains(out, "key_hash = $1 ") {
+		t.Errorf("placeholders missing:\n%s", out)
+	}
+}
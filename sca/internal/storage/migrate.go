@@ -0,0 +1,321 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"sca/migrations"
+	"sca/sca/internal/logging"
+
+	"gorm.io/gorm"
+)
+
+// migrationLockKey is the pg_advisory_lock key held while migrating so that
+// replicas booting at the same time don't apply the same migration twice.
+const migrationLockKey int64 = 0x5ca_0001
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a versioned up/down pair loaded from the migrations source.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// AppliedMigration is a row of the schema_migrations table.
+type AppliedMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	Checksum  string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (AppliedMigration) TableName() string { return "schema_migrations" }
+
+// MigrationStatus describes a known migration and whether it is applied.
+type MigrationStatus struct {
+	Version          int
+	Name             string
+	Applied          bool
+	AppliedAt        *time.Time
+	ChecksumMismatch bool
+}
+
+// MigrationsFS returns the migrations source: dir when set, otherwise the
+// copy embedded into the binary.
+func MigrationsFS(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	return migrations.FS
+}
+
+// LoadMigrations discovers NNN_name.up.sql / NNN_name.down.sql files at the
+// root of fsys and returns them ordered by version.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		parts := migrationFile.FindStringSubmatch(e.Name())
+		if parts == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(parts[1])
+		b, err := fs.ReadFile(fsys, path.Join(".", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = m
+		} else if m.Name != parts[2] {
+			return nil, fmt.Errorf("migration %03d has conflicting names %q and %q", version, m.Name, parts[2])
+		}
+		if parts[3] == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %03d_%s has no up file", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Migrator applies and reverts migrations, tracking them in schema_migrations.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB, fsys fs.FS) (*Migrator, error) {
+	list, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: list}, nil
+}
+
+// Up applies every pending migration and returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	n := 0
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if a, ok := applied[mig.Version]; ok {
+				if a.Checksum != mig.Checksum {
+					return fmt.Errorf("migration %03d_%s was modified after being applied (checksum mismatch)", mig.Version, mig.Name)
+				}
+				continue
+			}
+			if err := m.apply(conn, mig); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Down reverts the last steps applied migrations and returns how many were reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	n := 0
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && n < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if err := m.revert(conn, mig); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Redo reverts and re-applies the most recently applied migration.
+func (m *Migrator) Redo(ctx context.Context) error {
+	return m.locked(ctx, func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if err := m.revert(conn, mig); err != nil {
+				return err
+			}
+			return m.apply(conn, mig)
+		}
+		return errors.New("no applied migrations to redo")
+	})
+}
+
+// Status lists every known migration with its applied state.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn := m.db.WithContext(ctx)
+	if err := ensureMigrationsTable(conn); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(conn)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if a, ok := applied[mig.Version]; ok {
+			at := a.AppliedAt
+			st.Applied = true
+			st.AppliedAt = &at
+			st.ChecksumMismatch = a.Checksum != mig.Checksum
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// Latest returns the newest known migration, or ok=false when there are
+// none.
+func (m *Migrator) Latest() (Migration, bool) {
+	if len(m.migrations) == 0 {
+		return Migration{}, false
+	}
+	return m.migrations[len(m.migrations)-1], true
+}
+
+// VerifyLatest checks that the newest known migration is recorded in
+// schema_migrations with a matching checksum. Unlike Status it never
+// creates the table, so it is safe to call from health checks.
+func (m *Migrator) VerifyLatest(ctx context.Context) error {
+	latest, ok := m.Latest()
+	if !ok {
+		return nil
+	}
+	var row AppliedMigration
+	err := m.db.WithContext(ctx).Where("version = ?", latest.Version).Limit(1).Find(&row).Error
+	switch {
+	case err != nil:
+		return err
+	case row.Version == 0:
+		return fmt.Errorf("migration %03d_%s is not applied", latest.Version, latest.Name)
+	case row.Checksum != latest.Checksum:
+		return fmt.Errorf("migration %03d_%s was modified after it was applied", latest.Version, latest.Name)
+	}
+	return nil
+}
+
+// locked runs fn on a single pooled connection holding the migration
+// advisory lock, so the lock and the migration statements share a session.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockKey)
+		if err := ensureMigrationsTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func (m *Migrator) applied(conn *gorm.DB) (map[int]AppliedMigration, error) {
+	var rows []AppliedMigration
+	if err := conn.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[int]AppliedMigration, len(rows))
+	for _, r := range rows {
+		out[r.Version] = r
+	}
+	return out, nil
+}
+
+func (m *Migrator) apply(conn *gorm.DB, mig Migration) error {
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(mig.Up).Error; err != nil {
+			return err
+		}
+		return tx.Create(&AppliedMigration{
+			Version:   mig.Version,
+			Name:      mig.Name,
+			Checksum:  mig.Checksum,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("apply %03d_%s: %w", mig.Version, mig.Name, err)
+	}
+	logging.From(conn.Statement.Context).Info("migration applied", "version", mig.Version, "name", mig.Name)
+	return nil
+}
+
+func (m *Migrator) revert(conn *gorm.DB, mig Migration) error {
+	if mig.Down == "" {
+		return fmt.Errorf("migration %03d_%s has no down file", mig.Version, mig.Name)
+	}
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(mig.Down).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&AppliedMigration{}, mig.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("revert %03d_%s: %w", mig.Version, mig.Name, err)
+	}
+	logging.From(conn.Statement.Context).Info("migration reverted", "version", mig.Version, "name", mig.Name)
+	return nil
+}
+
+func ensureMigrationsTable(conn *gorm.DB) error {
+	return conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+version INT PRIMARY KEY,
+name TEXT NOT NULL,
+checksum TEXT NOT NULL,
+applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`).Error
+}